@@ -10,6 +10,7 @@ import (
 	"blog-service/internal/grpc"
 	"blog-service/internal/repository"
 	"blog-service/internal/service"
+	"userdirectory"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
@@ -33,7 +34,10 @@ func main() {
 
 	blogRepo := repository.NewBlogRepository(mongoDB)
 
-	blogService := service.NewBlogService(blogRepo)
+	// korisnicka imena autora i komentatora iz stakeholders servisa (USER_DIRECTORY_URL)
+	userDirectory := userdirectory.New(userdirectory.Config{})
+
+	blogService := service.NewBlogService(blogRepo, userDirectory)
 
 	blogHandler := api.NewHandler(blogService)
 
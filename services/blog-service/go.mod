@@ -11,6 +11,7 @@ require (
 	go.mongodb.org/mongo-driver v1.15.0
 	google.golang.org/grpc v1.76.0
 	google.golang.org/protobuf v1.36.10
+	userdirectory v0.0.0
 )
 
 require (
@@ -29,3 +30,5 @@ require (
 	golang.org/x/text v0.27.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250804133106-a7a43d27e69b // indirect
 )
+
+replace userdirectory => ../userdirectory
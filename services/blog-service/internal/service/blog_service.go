@@ -15,6 +15,7 @@ import (
 	"blog-service/internal/dto"
 	"blog-service/internal/models"
 	"blog-service/internal/repository"
+	"userdirectory"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -26,42 +27,21 @@ import (
 
 // BlogService sadrži reference na repository.
 type BlogService struct {
-	Repo repository.BlogRepository
+	Repo  repository.BlogRepository
+	Users *userdirectory.Client
 }
 
 // NewBlogService kreira novu instancu BlogService-a.
-func NewBlogService(repo repository.BlogRepository) *BlogService {
-	return &BlogService{Repo: repo}
+func NewBlogService(repo repository.BlogRepository, users *userdirectory.Client) *BlogService {
+	return &BlogService{Repo: repo, Users: users}
 }
 
 // CreateBlog kreira novi blog.
 func (s *BlogService) CreateBlog(ctx context.Context, req dto.CreateBlogRequest, authorID uint) (*models.Blog, error) {
 
-	var authorUsername string
+	// ime autora se cuva uz blog, ako direktorijum korisnika nije dostupan blog se ipak kreira
+	authorUsername := s.Users.Username(ctx, authorID, "Unknown Author")
 
-	authURL := fmt.Sprintf("http://auth-service:8084/api/v1/auth/user/%d", authorID)
-	resp, err := http.Get(authURL)
-    
-	if err != nil {
-		log.Printf("Warning: Auth service unreachable while creating blog for ID %d: %v", authorID, err)
-		authorUsername = "Unknown Author" // Postavi default
-	} else {
-        defer resp.Body.Close()
-        if resp.StatusCode == http.StatusOK {
-            var userData struct {
-                Username string `json:"username"`
-            }
-            if err := json.NewDecoder(resp.Body).Decode(&userData); err == nil {
-                authorUsername = userData.Username
-            } else {
-                log.Printf("Warning: Failed to parse username from auth response (%s) for ID %d: %v", resp.Status, authorID, err)
-                authorUsername = "Unknown Author"
-            }
-        } else {
-            log.Printf("Warning: Auth service returned status %s for ID %d", resp.Status, authorID)
-            authorUsername = "Unknown Author"
-        }
-	}
 	 // 1. Definišemo ekstenzije koje želimo da naš parser podržava
 	 extensions := mdparser.CommonExtensions | mdparser.AutoHeadingIDs | mdparser.Strikethrough
     
@@ -100,29 +80,15 @@ func (s *BlogService) CreateBlog(ctx context.Context, req dto.CreateBlogRequest,
 // AddComment dodaje komentar u blog.
 func (s *BlogService) AddComment(ctx context.Context, blogID primitive.ObjectID, req dto.AddCommentRequest, authorID uint) (*models.Comment, error) {
 
-	authURL := fmt.Sprintf("http://auth-service:8084/api/v1/auth/user/%d", authorID)
-    resp, err := http.Get(authURL)
-    if err != nil || resp.StatusCode != http.StatusOK {
-        return nil, fmt.Errorf("failed to fetch username from auth service")
-    }
-	
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK{
-		return nil, fmt.Errorf("auth service returned status: %s", resp.Status)
+	author, err := s.Users.Get(ctx, authorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch comment author: %w", err)
 	}
 
-    var userData struct {
-        Username string `json:"username"`
-    }
-    if err := json.NewDecoder(resp.Body).Decode(&userData); err != nil {
-        return nil, fmt.Errorf("failed to parse username from auth response: %w", err)
-    }
-
 	newComment := models.Comment{
 		ID:        primitive.NewObjectID(),
 		AuthorID:  authorID,
-		AuthorUsername: userData.Username,
+		AuthorUsername: author.Username,
 		Text:      req.Text,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
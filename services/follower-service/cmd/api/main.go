@@ -8,6 +8,7 @@ import (
 	"soa-tourist-app/follower-service/internal/database"
 	"soa-tourist-app/follower-service/internal/repository"
 	"soa-tourist-app/follower-service/internal/service"
+	"userdirectory"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
@@ -33,7 +34,9 @@ func main() {
 	// defer driver.Close(context.Background()) // defer se sada ne koristi jer server radi non-stop
 
 	repo := repository.NewFollowerRepository(driver)
-	followerService := service.NewFollowerService(repo)
+	// profili preporucenih korisnika iz stakeholders servisa (USER_DIRECTORY_URL)
+	userDirectory := userdirectory.New(userdirectory.Config{})
+	followerService := service.NewFollowerService(repo, userDirectory)
 	handler := api.NewHandler(followerService)
 
 	// Ruter
@@ -6,10 +6,13 @@ require (
 	github.com/gorilla/handlers v1.5.2
 	github.com/gorilla/mux v1.8.1
 	github.com/neo4j/neo4j-go-driver/v5 v5.28.3
+	github.com/sirupsen/logrus v1.9.3
+	userdirectory v0.0.0
 )
 
 require (
 	github.com/felixge/httpsnoop v1.0.3 // indirect
-	github.com/sirupsen/logrus v1.9.3 // indirect
 	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
 )
+
+replace userdirectory => ../userdirectory
@@ -1,36 +1,22 @@
 package service
 
 import (
-	"encoding/json"
-	"fmt"
-	"strings"
+	"context"
 	"errors"
-	"net/http"
-	"time"    
+	"log"
 	"soa-tourist-app/follower-service/internal/repository"
 	"soa-tourist-app/follower-service/internal/dto"
-
-
+	"userdirectory"
 )
 
 type FollowerService struct {
-	Repo *repository.FollowerRepository
-	HttpClient *http.Client 
-}
-// Pomoćna struktura za dekodiranje odgovora iz stakeholders servisa
-type StakeholderUser struct {
-	ID           uint   `json:"id"`
-	Username     string `json:"username"`
-	FirstName    string `json:"first_name"`
-	LastName     string `json:"last_name"`
-	ProfileImage string `json:"profile_image"`
+	Repo  *repository.FollowerRepository
+	Users *userdirectory.Client
 }
 
 // NewFollowerService kreira novu instancu servisa
-func NewFollowerService(repo *repository.FollowerRepository) *FollowerService {
-	return &FollowerService{Repo: repo,
-						   HttpClient: &http.Client{Timeout: 10 * time.Second}, 
-	}
+func NewFollowerService(repo *repository.FollowerRepository, users *userdirectory.Client) *FollowerService {
+	return &FollowerService{Repo: repo, Users: users}
 }
 
 // Follow proverava logiku i poziva repozitorijum da zaprati korisnika
@@ -60,27 +46,17 @@ func (s *FollowerService) GetRecommendations(currentUserID uint) ([]dto.Recommen
 		return []dto.RecommendationDTO{}, nil
 	}
 
-	var ids []string
-	for _, rec := range recommendedUsers {
-		ids = append(ids, fmt.Sprint(rec.UserID))
+	ids := make([]uint, len(recommendedUsers))
+	for i, rec := range recommendedUsers {
+		ids[i] = rec.UserID
 	}
-	idsQueryParam := strings.Join(ids, ",")
-
-	stakeholdersURL := fmt.Sprintf("http://stakeholders-service:8080/api/v1/users/batch?ids=%s", idsQueryParam)
-	resp, err := s.HttpClient.Get(stakeholdersURL)
+	// preporuke bez profila se preskacu, pa je delimican odgovor bolji od greske
+	profilesMap, err := s.Users.Lookup(context.Background(), ids)
 	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var userProfiles []StakeholderUser
-	if err := json.NewDecoder(resp.Body).Decode(&userProfiles); err != nil {
-		return nil, err
-	}
-
-	profilesMap := make(map[uint]StakeholderUser)
-	for _, profile := range userProfiles {
-		profilesMap[profile.ID] = profile
+		if len(profilesMap) == 0 {
+			return nil, err
+		}
+		log.Printf("Warning: some recommended users could not be resolved: %v", err)
 	}
 
 	var finalRecommendations []dto.RecommendationDTO
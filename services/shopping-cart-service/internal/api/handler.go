@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"fmt"
 
@@ -61,13 +62,18 @@ func (h *Handler) Checkout(w http.ResponseWriter, r *http.Request) {
 	userID := GetUserID(r)
 
 	// U realnosti bi ovde išla validacija placanja??
-	
-	resp, err := h.Service.Checkout(r.Context(), userID)
+	authHeader := r.Header.Get("Authorization")
+
+	resp, err := h.Service.Checkout(r.Context(), userID, authHeader)
 	if err != nil {
-		if err.Error() == "shopping cart is empty" {
+		if errors.Is(err, service.ErrCartEmpty) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		if errors.Is(err, service.ErrCartChanged) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, "Checkout failed: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
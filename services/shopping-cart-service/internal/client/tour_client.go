@@ -2,16 +2,21 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 )
 
+// ErrTourNotFound znaci da tura ne postoji ili vise nije dostupna kupcu (npr. obrisana)
+var ErrTourNotFound = errors.New("tour not found")
+
 // TourDetails je struktura koja predstavlja odgovor od tour-service
 // ID je 'uint' da bi se poklopilo sa odgovorom tour-servisa
 type TourDetails struct {
-	ID    uint    `json:"id"`
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
+	ID      uint    `json:"id"`
+	Name    string  `json:"name"`
+	Price   float64 `json:"price"`
+	Version int     `json:"version"`
 }
 
 // TourServiceClient je odgovoran za komunikaciju sa tour-service
@@ -47,6 +52,9 @@ func (c *TourServiceClient) GetTourDetails(tourID string, authorizationHeader st
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrTourNotFound
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("tour service returned non-200 status: %d", resp.StatusCode)
 	}
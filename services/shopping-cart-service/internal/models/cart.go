@@ -27,5 +27,7 @@ type TourPurchaseToken struct {
 	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	UserID       uint               `bson:"userId" json:"userId"`
 	TourID       string             `bson:"tourId" json:"tourId"`
+	TourVersion  int                `bson:"tourVersion" json:"tourVersion"` // Verzija ture u trenutku kupovine
+	Price        float64            `bson:"price" json:"price"`
 	PurchaseTime time.Time          `bson:"purchaseTime" json:"purchaseTime"`
 }
@@ -7,6 +7,7 @@ import (
 	"log"
 	"time"
 	"strconv"
+	"strings"
 
 	"shopping-cart-service/internal/client"
 	"shopping-cart-service/internal/dto"
@@ -16,6 +17,13 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ErrCartEmpty se vraca kada korpa nema stavki za kupovinu
+var ErrCartEmpty = errors.New("shopping cart is empty")
+
+// ErrCartChanged znaci da se tura u korpi promenila (cena ili dostupnost) od dodavanja,
+// korpa je azurirana i kupac treba da je pregleda pre ponovne kupovine
+var ErrCartChanged = errors.New("shopping cart changed")
+
 // CartService sadrži reference na repository.
 type CartService struct {
 	Repo repository.CartRepository
@@ -93,23 +101,56 @@ func (s *CartService) AddItemToCart(ctx context.Context, userID uint, req dto.Ad
 }
 
 // Checkout obrađuje kupovinu: kreira tokene i briše korpu.
-func (s *CartService) Checkout(ctx context.Context, userID uint) (*dto.TourPurchaseResponse, error) {
+func (s *CartService) Checkout(ctx context.Context, userID uint, authHeader string) (*dto.TourPurchaseResponse, error) {
 	cart, err := s.Repo.GetCartByUserID(ctx, userID)
 	if err != nil {
 		return nil, errors.New("failed to retrieve shopping cart for checkout")
 	}
 	if cart == nil || len(cart.Items) == 0 {
-		return nil, errors.New("shopping cart is empty")
+		return nil, ErrCartEmpty
 	}
 
 	// 1. Kreiranje tokena za svaku stavku
-	tokens := make([]models.TourPurchaseToken, len(cart.Items))
-	for i, item := range cart.Items {
-		tokens[i] = models.TourPurchaseToken{
-			ID:     primitive.NewObjectID(),
-			UserID: userID,
-			TourID: item.TourID,
+	// verzija i cena se proveravaju u trenutku kupovine, tura je mogla da bude ponovo publishovana ili obrisana dok je bila u korpi
+	purchaseTime := time.Now()
+	tokens := make([]models.TourPurchaseToken, 0, len(cart.Items))
+	available := make([]models.OrderItem, 0, len(cart.Items))
+	var changes []string
+	for _, item := range cart.Items {
+		tourDetails, err := s.TourServiceClient.GetTourDetails(item.TourID, authHeader)
+		if errors.Is(err, client.ErrTourNotFound) {
+			changes = append(changes, fmt.Sprintf("%q is no longer available and was removed", item.Name))
+			continue
+		}
+		if err != nil {
+			log.Printf("ERROR: Failed to get tour details for TourID %s at checkout. Error: %v", item.TourID, err)
+			return nil, fmt.Errorf("could not retrieve tour information for %q", item.Name)
+		}
+
+		if tourDetails.Price != item.Price {
+			changes = append(changes, fmt.Sprintf("price of %q changed from %.2f to %.2f", item.Name, item.Price, tourDetails.Price))
+			item.Price = tourDetails.Price
+		}
+		available = append(available, item)
+
+		tokens = append(tokens, models.TourPurchaseToken{
+			ID:           primitive.NewObjectID(),
+			UserID:       userID,
+			TourID:       item.TourID,
+			TourVersion:  tourDetails.Version,
+			Price:        tourDetails.Price,
+			PurchaseTime: purchaseTime,
+		})
+	}
+
+	if len(changes) > 0 {
+		// korpa se azurira da sledeci pokusaj kupovine prodje sa cenama koje kupac vidi
+		cart.Items = available
+		cart.Total = calculateTotal(cart.Items)
+		if err := s.Repo.UpdateCart(ctx, cart); err != nil {
+			return nil, fmt.Errorf("failed to update cart: %w", err)
 		}
+		return nil, fmt.Errorf("%w: %s", ErrCartChanged, strings.Join(changes, "; "))
 	}
 
 	// 2. Snimanje tokena u bazu
@@ -1,16 +1,21 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"tour-service/internal/api"
 	"tour-service/internal/clients"
 	"tour-service/internal/database"
+	"tour-service/internal/interfaces"
 	"tour-service/internal/repository"
 	"tour-service/internal/service"
+	"userdirectory"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
@@ -23,36 +28,83 @@ func main() {
 	keyPointRepo := repository.NewKeyPointRepository(db)
 	reviewRepo := repository.NewReviewRepository(db)
 	tourExecutionRepo := repository.NewTourExecutionRepository(db)
+	badgeRepo := repository.NewBadgeRepository(db)
+
+	// ROUTING_PROVIDER=osrm koristi OSRM-kompatibilan API sa OSRM_URL, inace se racuna vazdusna linija
+	var routingProvider interfaces.RoutingProvider = service.NewHaversineRoutingProvider()
+	if os.Getenv("ROUTING_PROVIDER") == "osrm" {
+		osrmURL := os.Getenv("OSRM_URL")
+		if osrmURL == "" {
+			osrmURL = "http://osrm:5000"
+		}
+		routingProvider = clients.NewOSRMRoutingProvider(osrmURL)
+		log.Printf("Using OSRM routing provider at %s", osrmURL)
+	}
+	routePlanner := service.NewRoutePlanner(routingProvider)
 
 	shoppingCartClient := clients.NewShoppingCartClient("http://shopping-cart-service:8081")
-	tourService := service.NewTourService(tourRepo, shoppingCartClient)
-	keyPointService := service.NewKeyPointService(keyPointRepo, tourRepo)
-	reviewService := service.NewReviewService(reviewRepo, tourRepo)
+	tourService := service.NewTourService(tourRepo, shoppingCartClient, routePlanner)
+	keyPointService := service.NewKeyPointService(keyPointRepo, tourRepo, routePlanner)
 	purchaseChecker, err := clients.NewGRPCPurchaseChecker("shopping-cart-service:50051")
 	if err != nil {
 		log.Fatalf("Failed to create gRPC client: %v", err)
 	}
-	tourExecutionService := service.NewTourExecutionService(tourExecutionRepo, purchaseChecker)
+	// korisnicka imena iz stakeholders servisa (USER_DIRECTORY_URL), kesirana i spajana u batch pozive
+	userDirectory := userdirectory.New(userdirectory.Config{})
+	reviewService := service.NewReviewService(reviewRepo, tourRepo, tourExecutionRepo, purchaseChecker, userDirectory)
+	executionEvents := service.NewExecutionEventHub()
+	badgeService := service.NewBadgeService(badgeRepo)
+	tourExecutionService := service.NewTourExecutionService(tourExecutionRepo, purchaseChecker, executionEvents, badgeService)
+
+	// izvrsavanja bez aktivnosti duze od EXECUTION_INACTIVITY_TIMEOUT se oznacavaju kao napustena
+	expiryWorker := service.NewExecutionExpiryWorker(tourExecutionRepo, executionEvents,
+		durationFromEnv("EXECUTION_INACTIVITY_TIMEOUT", service.DefaultInactivityTimeout),
+		durationFromEnv("EXECUTION_EXPIRY_INTERVAL", service.DefaultExpiryInterval))
+	go expiryWorker.Run(context.Background())
 
 	apiHandler := api.NewHandler(tourService, keyPointService)
 	reviewHandler := api.NewReviewHandler(reviewService)
 	tourExecutionHandler := api.NewTourExecutionHandler(tourExecutionService)
+	badgeHandler := api.NewBadgeHandler(badgeService)
 
 	r := mux.NewRouter()
 	apiV1 := r.PathPrefix("/api/v1/tours").Subrouter()
+	apiV1.Use(api.AuthMiddleware)
 
-	// Tour routes - API Gateway sada radi JWT validaciju i prosleđuje X-User-* headere
+	// Tour routes - AuthMiddleware proverava JWT iz Authorization headera i upisuje korisnika u context
 	apiV1.HandleFunc("/create-tour", apiHandler.CreateTour).Methods("POST")
+	apiV1.HandleFunc("/import", apiHandler.ImportTour).Methods("POST")
 	apiV1.HandleFunc("", apiHandler.GetMyTours).Methods("GET")
 	apiV1.HandleFunc("/published", apiHandler.GetAllPublishedTours).Methods("GET")
+	apiV1.HandleFunc("/nearby", apiHandler.GetToursNearby).Methods("GET")
+	apiV1.HandleFunc("/badges", badgeHandler.GetBadgeProgress).Methods("GET") // mora pre /{tourId}
+	apiV1.HandleFunc("/users/{userId}/badges", badgeHandler.GetUserBadges).Methods("GET")
+	apiV1.HandleFunc("/my-reviews", reviewHandler.GetMyReviews).Methods("GET") // mora pre /{tourId}
+	apiV1.HandleFunc("/my-reviews/unread-replies", reviewHandler.GetUnreadReplyCount).Methods("GET")
 	apiV1.HandleFunc("/{tourId}", apiHandler.GetTourByID).Methods("GET")
+	apiV1.HandleFunc("/{tourId}", apiHandler.DeleteTour).Methods("DELETE")
+	apiV1.HandleFunc("/{tourId}/restore", apiHandler.RestoreTour).Methods("PUT")
+	apiV1.HandleFunc("/{tourId}/purge", apiHandler.PurgeTour).Methods("DELETE")
 	apiV1.HandleFunc("/{tourId}/publish", apiHandler.PublishTour).Methods("PUT")
 	apiV1.HandleFunc("/{tourId}/archive", apiHandler.ArchiveTour).Methods("PUT")
 	apiV1.HandleFunc("/{tourId}/activate", apiHandler.ActivateTour).Methods("PUT")
 	apiV1.HandleFunc("/{tourId}/duration", apiHandler.AddDuration).Methods("POST")
+	apiV1.HandleFunc("/{tourId}/price", apiHandler.UpdateTourPrice).Methods("PUT")
+	apiV1.HandleFunc("/{tourId}/price-history", apiHandler.GetPriceHistory).Methods("GET")
+	apiV1.HandleFunc("/{tourId}/export", apiHandler.ExportTour).Methods("GET")
+	apiV1.HandleFunc("/{tourId}/revision", apiHandler.CreateRevision).Methods("POST")
+	apiV1.HandleFunc("/{tourId}/clone", apiHandler.CloneTour).Methods("POST")
+	apiV1.HandleFunc("/{tourId}/completion-mode", apiHandler.UpdateCompletionMode).Methods("PUT")
+	apiV1.HandleFunc("/{tourId}/versions", apiHandler.GetTourVersions).Methods("GET")
+	apiV1.HandleFunc("/{tourId}/versions/{version}", apiHandler.GetTourVersion).Methods("GET")
+	apiV1.HandleFunc("/{tourId}/analytics", tourExecutionHandler.GetTourAnalytics).Methods("GET")
 
 	// KeyPoint routes
 	apiV1.HandleFunc("/{tourId}/keypoints", apiHandler.GetKeyPointsByTour).Methods("GET")
+	apiV1.HandleFunc("/{tourId}/keypoints", apiHandler.AppendKeyPoint).Methods("POST")
+	apiV1.HandleFunc("/{tourId}/keypoints/insert", apiHandler.InsertKeyPoint).Methods("POST")
+	apiV1.HandleFunc("/{tourId}/keypoints/order", apiHandler.ReorderKeyPoints).Methods("PUT")
+	apiV1.HandleFunc("/{tourId}/keypoints/secrets", apiHandler.GetKeyPointSecrets).Methods("GET")
 	apiV1.HandleFunc("/keypoints/{keyPointId}", apiHandler.UpdateKeyPoint).Methods("PUT")
 	apiV1.HandleFunc("/keypoints/{keyPointId}", apiHandler.DeleteKeyPoint).Methods("DELETE")
 
@@ -62,13 +114,25 @@ func main() {
 	apiV1.HandleFunc("/{tourId}/reviews/stats", reviewHandler.GetTourRatingStats).Methods("GET")
 	apiV1.HandleFunc("/reviews/{reviewId}", reviewHandler.UpdateReview).Methods("PUT")
 	apiV1.HandleFunc("/reviews/{reviewId}", reviewHandler.DeleteReview).Methods("DELETE")
-	apiV1.HandleFunc("/my-reviews", reviewHandler.GetMyReviews).Methods("GET")
+	apiV1.HandleFunc("/reviews/{reviewId}/reply", reviewHandler.ReplyToReview).Methods("PUT")
+	apiV1.HandleFunc("/reviews/{reviewId}/reply", reviewHandler.DeleteReply).Methods("DELETE")
+	apiV1.HandleFunc("/reviews/{reviewId}/reply/read", reviewHandler.MarkReplyRead).Methods("PUT")
+	apiV1.HandleFunc("/reviews/{reviewId}/vote", reviewHandler.VoteReview).Methods("PUT")
+	apiV1.HandleFunc("/reviews/{reviewId}/vote", reviewHandler.RemoveVote).Methods("DELETE")
+	apiV1.HandleFunc("/reviews/{reviewId}/report", reviewHandler.ReportReview).Methods("POST")
+	apiV1.HandleFunc("/reviews/moderation", reviewHandler.GetModerationQueue).Methods("GET")
+	apiV1.HandleFunc("/reviews/{reviewId}/moderation", reviewHandler.ModerateReview).Methods("PUT")
 
 	// TourExecution routes
 	apiV1.HandleFunc("/{tourId}/start", tourExecutionHandler.StartTour).Methods("POST")
 	apiV1.HandleFunc("/executions/{executionId}/check-position", tourExecutionHandler.CheckPosition).Methods("POST")
+	apiV1.HandleFunc("/executions/{executionId}/positions/sync", tourExecutionHandler.SyncPositions).Methods("POST")
 	apiV1.HandleFunc("/executions/{executionId}/complete", tourExecutionHandler.CompleteTour).Methods("PUT")
 	apiV1.HandleFunc("/executions/{executionId}/abandon", tourExecutionHandler.AbandonTour).Methods("PUT")
+	apiV1.HandleFunc("/executions/{executionId}/events", tourExecutionHandler.StreamExecutionEvents).Methods("GET")
+	apiV1.HandleFunc("/executions/{executionId}/secrets", tourExecutionHandler.GetRevealedSecrets).Methods("GET")
+	apiV1.HandleFunc("/executions/{executionId}/trail", tourExecutionHandler.GetExecutionTrail).Methods("GET")
+	apiV1.HandleFunc("/executions/{executionId}/trail/stats", tourExecutionHandler.GetExecutionTrailStats).Methods("GET")
 	apiV1.HandleFunc("/executions/active/{tourId}", tourExecutionHandler.GetActiveExecution).Methods("GET")
 	apiV1.HandleFunc("/executions/{executionId}", tourExecutionHandler.GetExecutionDetails).Methods("GET")
 	apiV1.HandleFunc("/executions/tour/{tourId}", tourExecutionHandler.GetExecutionsByTour).Methods("GET")
@@ -88,3 +152,17 @@ func main() {
 	fmt.Println("Tour service running on internal port 8080")
 	log.Fatal(http.ListenAndServe(":8080", corsHandler))
 }
+
+// durationFromEnv cita Go duration (npr. "2h", "45m") iz env varijable, uz podrazumevanu vrednost
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid %s=%q, using %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
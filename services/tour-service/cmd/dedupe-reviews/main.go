@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"tour-service/internal/database"
+)
+
+// dedupe-reviews pronalazi turiste sa vise recenzija iste ture, sto sprecava pravljenje unique indexa
+// (tour_id, tourist_id). Bez -apply samo ispisuje sta bi bilo obrisano; sa -apply ostavlja poslednju
+// izmenjenu recenziju. Radi bez migracije, jer servis ne krece dok duplikati postoje.
+func main() {
+	apply := flag.Bool("apply", false, "delete the duplicate reviews instead of only listing them")
+	flag.Parse()
+
+	db := database.Connect()
+
+	duplicates, err := database.FindDuplicateReviews(db)
+	if err != nil {
+		log.Fatalf("Failed to find duplicate reviews: %v", err)
+	}
+	if len(duplicates) == 0 {
+		log.Println("No duplicate reviews found")
+		return
+	}
+	for _, d := range duplicates {
+		updated := "never"
+		if d.UpdatedAt != nil {
+			updated = d.UpdatedAt.Format(time.RFC3339)
+		}
+		log.Printf("tour %d, tourist %d: review %d (rating %d, updated %s) duplicates review %d",
+			d.TourID, d.TouristID, d.ID, d.Rating, updated, d.KeptID)
+	}
+	if !*apply {
+		log.Printf("%d duplicate reviews found, run with -apply to delete them", len(duplicates))
+		return
+	}
+
+	removed, err := database.RemoveDuplicateReviews(db)
+	if err != nil {
+		log.Fatalf("Failed to remove duplicate reviews: %v", err)
+	}
+	log.Printf("Removed %d duplicate reviews", len(removed))
+
+	// ocene tura se racunaju posle migracije, kada servis ponovo krene
+	log.Println("Start the service and run ./repair-ratings to recalculate tour ratings")
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"log"
+
+	"tour-service/internal/database"
+	"tour-service/internal/repository"
+)
+
+// repair-ratings ponovo racuna AverageRating i ReviewCount svih tura iz tabele reviews,
+// npr. posle uvodjenja kolona ili rucnih izmena recenzija u bazi
+func main() {
+	db := database.InitDB()
+	tourRepo := repository.NewTourRepository(db)
+
+	corrected, err := tourRepo.RecalculateAllRatings()
+	if err != nil {
+		log.Fatalf("Failed to recalculate tour ratings: %v", err)
+	}
+	log.Printf("Tour ratings recalculated, %d tours corrected", corrected)
+}
@@ -11,6 +11,7 @@ require (
 	google.golang.org/protobuf v1.36.10
 	gorm.io/driver/postgres v1.6.0
 	gorm.io/gorm v1.31.0
+	userdirectory v0.0.0
 )
 
 require (
@@ -28,3 +29,5 @@ require (
 	golang.org/x/text v0.27.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250804133106-a7a43d27e69b // indirect
 )
+
+replace userdirectory => ../userdirectory
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"tour-service/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+type BadgeHandler struct {
+	badgeService *service.BadgeService
+}
+
+func NewBadgeHandler(badgeService *service.BadgeService) *BadgeHandler {
+	return &BadgeHandler{badgeService: badgeService}
+}
+
+// GetUserBadges handles retrieving the badges a user has earned
+func (h *BadgeHandler) GetUserBadges(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID, err := strconv.ParseUint(vars["userId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	badges, err := h.badgeService.GetUserBadges(uint(userID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(badges)
+}
+
+// GetBadgeProgress handles retrieving every available badge with the current user's progress
+func (h *BadgeHandler) GetBadgeProgress(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	progress, err := h.badgeService.GetBadgeProgress(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(progress)
+}
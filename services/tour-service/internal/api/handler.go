@@ -2,8 +2,13 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 	"tour-service/internal/dto"
 	"tour-service/internal/service"
 
@@ -22,6 +27,21 @@ func NewHandler(s *service.TourService, kps *service.KeyPointService) *Handler {
 	}
 }
 
+// tourErrorStatus mapira greske operacija nad turom na HTTP status
+func tourErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrTourNotFound), errors.Is(err, service.ErrKeyPointNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrNotTourAuthor):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrTourNotDeleted), errors.Is(err, service.ErrNotDraft):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidQuery), errors.Is(err, service.ErrInvalidRequest):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
 // CreateTour kreira turu SA keypointsima
 func (h *Handler) CreateTour(w http.ResponseWriter, r *http.Request) {
 	userID, _ := r.Context().Value("userID").(uint)
@@ -55,11 +75,63 @@ func (h *Handler) GetMyTours(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(tours)
 }
 
-// vraca sve publishovane ture
+// vraca publishovane ture filtrirane, sortirane i podeljene na strane
+//
+//	?difficulty=Easy,Medium&tags=a,b&tagMatch=all&minPrice=&maxPrice=&minDistance=&maxDistance=&minRating=
+//	&transportType=walking&q=text&sort=newest|price_asc|price_desc|distance_asc|distance_desc|rating&page=1&limit=20
 func (h *Handler) GetAllPublishedTours(w http.ResponseWriter, r *http.Request) {
-	tours, err := h.TourService.GetAllPublishedTours()
+	query, err := parseTourSearchQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.TourService.SearchPublishedTours(query)
+	if errors.Is(err, service.ErrInvalidQuery) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Failed to retrieve published tours", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// vraca publishovane ture u blizini lokacije
+//
+//	?lat=45.25&lng=19.84&radius=5&startOnly=true&limit=20
+func (h *Handler) GetToursNearby(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	lat, err := strconv.ParseFloat(q.Get("lat"), 64)
+	if err != nil {
+		http.Error(w, "Invalid lat", http.StatusBadRequest)
+		return
+	}
+	lng, err := strconv.ParseFloat(q.Get("lng"), 64)
+	if err != nil {
+		http.Error(w, "Invalid lng", http.StatusBadRequest)
+		return
+	}
+	radius, err := strconv.ParseFloat(q.Get("radius"), 64)
+	if err != nil {
+		http.Error(w, "Invalid radius", http.StatusBadRequest)
+		return
+	}
+	limit := 0
+	if raw := q.Get("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	startOnly := q.Get("startOnly") == "true"
+
+	tours, err := h.TourService.FindToursNearby(lat, lng, radius, startOnly, limit)
+	if err != nil {
+		http.Error(w, err.Error(), tourErrorStatus(err))
 		return
 	}
 
@@ -67,6 +139,124 @@ func (h *Handler) GetAllPublishedTours(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(tours)
 }
 
+// parsira query parametre pretrage publishovanih tura
+func parseTourSearchQuery(r *http.Request) (dto.TourSearchQuery, error) {
+	q := r.URL.Query()
+	query := dto.TourSearchQuery{
+		Difficulties:  splitQueryList(q.Get("difficulty")),
+		Tags:          splitQueryList(q.Get("tags")),
+		MatchAllTags:  q.Get("tagMatch") == "all",
+		TransportType: q.Get("transportType"),
+		Text:          strings.TrimSpace(q.Get("q")),
+		Sort:          q.Get("sort"),
+	}
+
+	floats := map[string]**float64{
+		"minPrice":    &query.MinPrice,
+		"maxPrice":    &query.MaxPrice,
+		"minDistance": &query.MinDistance,
+		"maxDistance": &query.MaxDistance,
+		"minRating":   &query.MinRating,
+	}
+	for name, target := range floats {
+		raw := q.Get(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return query, fmt.Errorf("invalid %s", name)
+		}
+		*target = &value
+	}
+
+	ints := map[string]*int{
+		"page":  &query.Page,
+		"limit": &query.Limit,
+	}
+	for name, target := range ints {
+		raw := q.Get(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return query, fmt.Errorf("invalid %s", name)
+		}
+		*target = value
+	}
+
+	return query, nil
+}
+
+// deli listu odvojenu zarezima i izbacuje prazne vrednosti
+func splitQueryList(raw string) []string {
+	var values []string
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+// maxImportFileSize je najveca dozvoljena velicina uvezenog GPX/GeoJSON fajla
+const maxImportFileSize = 10 << 20
+
+// kreira draft turu iz uploadovanog GPX ili GeoJSON fajla (multipart polje "file")
+func (h *Handler) ImportTour(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(uint)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
+	if err := r.ParseMultipartForm(maxImportFileSize); err != nil {
+		http.Error(w, "Invalid multipart form or file too large", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusBadRequest)
+		return
+	}
+
+	req := dto.ImportTourRequest{
+		Name:        r.FormValue("name"),
+		Description: r.FormValue("description"),
+		Difficulty:  r.FormValue("difficulty"),
+		Tags:        splitQueryList(r.FormValue("tags")),
+	}
+	if raw := r.FormValue("maxKeyPoints"); raw != "" {
+		if req.MaxKeyPoints, err = strconv.Atoi(raw); err != nil {
+			http.Error(w, "Invalid maxKeyPoints", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tour, err := h.TourService.ImportTour(userID, header.Filename, data, req)
+	if err != nil {
+		var importErr *service.ImportError
+		if errors.As(err, &importErr) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(importErr)
+			return
+		}
+		http.Error(w, "Failed to import tour: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tour)
+}
+
 // KEYPOINT metode:
 //
 //	vraca sve key pointove za specificnu turu
@@ -89,6 +279,110 @@ func (h *Handler) GetKeyPointsByTour(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(keyPoints)
 }
 
+// vraca keypointe ture sa tajnim sadrzajem (samo autor ture)
+func (h *Handler) GetKeyPointSecrets(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(uint)
+	vars := mux.Vars(r)
+	tourID, err := strconv.ParseUint(vars["tourId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	keyPoints, err := h.KeyPointService.GetKeyPointSecrets(uint(tourID), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keyPoints)
+}
+
+// dodaje keypoint na kraj postojece ture
+func (h *Handler) AppendKeyPoint(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(uint)
+	vars := mux.Vars(r)
+	tourID, err := strconv.ParseUint(vars["tourId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.CreateKeyPointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	keyPoint, err := h.KeyPointService.AppendKeyPoint(uint(tourID), userID, req)
+	if err != nil {
+		http.Error(w, "Failed to add key point: "+err.Error(), tourErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(keyPoint)
+}
+
+// ubacuje keypoint na poziciju "order", ostali keypointi se pomeraju
+func (h *Handler) InsertKeyPoint(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(uint)
+	vars := mux.Vars(r)
+	tourID, err := strconv.ParseUint(vars["tourId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.CreateKeyPointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Order < 1 {
+		http.Error(w, "order must be a positive position", http.StatusBadRequest)
+		return
+	}
+
+	keyPoint, err := h.KeyPointService.InsertKeyPoint(uint(tourID), userID, req)
+	if err != nil {
+		http.Error(w, "Failed to insert key point: "+err.Error(), tourErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(keyPoint)
+}
+
+// postavlja novi redosled svih keypointa ture
+func (h *Handler) ReorderKeyPoints(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(uint)
+	vars := mux.Vars(r)
+	tourID, err := strconv.ParseUint(vars["tourId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.ReorderKeyPointsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	keyPoints, err := h.KeyPointService.ReorderKeyPoints(uint(tourID), userID, req)
+	if err != nil {
+		http.Error(w, "Failed to reorder key points: "+err.Error(), tourErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keyPoints)
+}
+
 // azurira keypoint
 func (h *Handler) UpdateKeyPoint(w http.ResponseWriter, r *http.Request) {
 	userID, _ := r.Context().Value("userID").(uint)
@@ -109,7 +403,7 @@ func (h *Handler) UpdateKeyPoint(w http.ResponseWriter, r *http.Request) {
 
 	keyPoint, err := h.KeyPointService.UpdateKeyPoint(uint(keyPointID), userID, req)
 	if err != nil {
-		http.Error(w, "Failed to update key point: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to update key point: "+err.Error(), tourErrorStatus(err))
 		return
 	}
 
@@ -131,7 +425,7 @@ func (h *Handler) DeleteKeyPoint(w http.ResponseWriter, r *http.Request) {
 
 	err = h.KeyPointService.DeleteKeyPoint(uint(keyPointID), userID)
 	if err != nil {
-		http.Error(w, "Failed to delete key point: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to delete key point: "+err.Error(), tourErrorStatus(err))
 		return
 	}
 
@@ -168,6 +462,70 @@ func (h *Handler) AddDuration(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(duration)
 }
 
+// postavlja novu cenu ture
+func (h *Handler) UpdateTourPrice(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(uint)
+	vars := mux.Vars(r)
+	tourID, err := strconv.ParseUint(vars["tourId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.UpdatePriceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tour, err := h.TourService.UpdateTourPrice(uint(tourID), userID, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tour)
+}
+
+// vraca istoriju cena ture, ?at=RFC3339 vraca cenu vazecu u tom trenutku
+func (h *Handler) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(uint)
+	vars := mux.Vars(r)
+	tourID, err := strconv.ParseUint(vars["tourId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	var at *time.Time
+	if raw := r.URL.Query().Get("at"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, "Invalid at, expected RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		at = &parsed
+	}
+
+	isAdmin := GetUserRole(r) == AdminRole
+	history, err := h.TourService.GetPriceHistory(uint(tourID), userID, isAdmin, at)
+	switch {
+	case errors.Is(err, service.ErrTourNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrNotTourAuthor):
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
 // publishuje draftovanu turu
 func (h *Handler) PublishTour(w http.ResponseWriter, r *http.Request) {
 	userID, _ := r.Context().Value("userID").(uint)
@@ -249,11 +607,225 @@ func (h *Handler) GetTourByID(w http.ResponseWriter, r *http.Request) {
 
 	// Prosledi sve potrebne podatke servisu
 	tour, err := h.TourService.GetTourByID(uint(tourID), userID, authHeader)
+	if err != nil {
+		http.Error(w, err.Error(), tourErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tour)
+}
+
+// izvozi rutu ture kao GPX, KML ili GeoJSON (?format= ima prednost nad Accept headerom)
+func (h *Handler) ExportTour(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tourID, err := strconv.ParseUint(vars["tourId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	format := service.RouteFormatFromAccept(r.Header.Get("Accept"))
+	if raw := r.URL.Query().Get("format"); raw != "" {
+		if format, err = service.ParseRouteFormat(raw); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID, _ := r.Context().Value("userID").(uint)
+	authHeader := r.Header.Get("Authorization")
+
+	body, err := h.TourService.ExportTour(uint(tourID), userID, authHeader, format)
+	if err != nil {
+		http.Error(w, err.Error(), tourErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"tour-%d.%s\"", tourID, format))
+	w.Write(body)
+}
+
+// kreira (ili vraca postojecu) draft reviziju publishovane ture
+func (h *Handler) CreateRevision(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(uint)
+	vars := mux.Vars(r)
+	tourID, err := strconv.ParseUint(vars["tourId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	revision, err := h.TourService.CreateRevision(uint(tourID), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revision)
+}
+
+// vraca listu publishovanih verzija ture
+func (h *Handler) GetTourVersions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tourID, err := strconv.ParseUint(vars["tourId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	versions, err := h.TourService.GetTourVersions(uint(tourID))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+// vraca turu onakvu kakva je bila u odredjenoj verziji
+func (h *Handler) GetTourVersion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tourID, err := strconv.ParseUint(vars["tourId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+	version, err := strconv.Atoi(vars["version"])
+	if err != nil {
+		http.Error(w, "Invalid version", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(uint)
+	authHeader := r.Header.Get("Authorization")
+
+	tour, err := h.TourService.GetTourVersion(uint(tourID), version, userID, authHeader)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tour)
+}
+
+// brise turu (draft trajno, publishovanu ili arhiviranu soft delete)
+func (h *Handler) DeleteTour(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(uint)
+	vars := mux.Vars(r)
+	tourID, err := strconv.ParseUint(vars["tourId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.TourService.DeleteTour(uint(tourID), userID); err != nil {
+		http.Error(w, err.Error(), tourErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// vraca obrisanu turu (samo administrator)
+func (h *Handler) RestoreTour(w http.ResponseWriter, r *http.Request) {
+	if GetUserRole(r) != AdminRole {
+		http.Error(w, "Forbidden: Only administrators can restore tours", http.StatusForbidden)
+		return
+	}
+
+	vars := mux.Vars(r)
+	tourID, err := strconv.ParseUint(vars["tourId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	tour, err := h.TourService.RestoreTour(uint(tourID))
+	if err != nil {
+		http.Error(w, err.Error(), tourErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tour)
+}
+
+// trajno brise obrisanu turu (samo administrator)
+func (h *Handler) PurgeTour(w http.ResponseWriter, r *http.Request) {
+	if GetUserRole(r) != AdminRole {
+		http.Error(w, "Forbidden: Only administrators can purge tours", http.StatusForbidden)
+		return
+	}
+
+	vars := mux.Vars(r)
+	tourID, err := strconv.ParseUint(vars["tourId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.TourService.PurgeTour(uint(tourID)); err != nil {
+		http.Error(w, err.Error(), tourErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// kopira turu autora u novi draft sa novim imenom
+func (h *Handler) CloneTour(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(uint)
+	vars := mux.Vars(r)
+	tourID, err := strconv.ParseUint(vars["tourId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.CloneTourRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tour, err := h.TourService.CloneTour(uint(tourID), userID, req)
+	if err != nil {
+		http.Error(w, "Failed to clone tour: "+err.Error(), tourErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tour)
+}
+
+// menja nacin obilaska keypointa (FreeOrder ili Sequential) draft ture
+func (h *Handler) UpdateCompletionMode(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(uint)
+	vars := mux.Vars(r)
+	tourID, err := strconv.ParseUint(vars["tourId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.UpdateCompletionModeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tour, err := h.TourService.UpdateCompletionMode(uint(tourID), userID, req)
+	if err != nil {
+		http.Error(w, "Failed to update completion mode: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(tour)
 }
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"tour-service/internal/service"
+)
+
+func TestTourErrorStatus(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{service.ErrTourNotFound, http.StatusNotFound},
+		{service.ErrKeyPointNotFound, http.StatusNotFound},
+		{service.ErrNotTourAuthor, http.StatusForbidden},
+		{service.ErrNotDraft, http.StatusConflict},
+		{fmt.Errorf("%w: only deleted tours can be purged", service.ErrTourNotDeleted), http.StatusConflict},
+		{fmt.Errorf("%w: invalid radius", service.ErrInvalidQuery), http.StatusBadRequest},
+		{fmt.Errorf("%w: tour name is required", service.ErrInvalidRequest), http.StatusBadRequest},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		if got := tourErrorStatus(c.err); got != c.status {
+			t.Errorf("tourErrorStatus(%q) = %d, expected %d", c.err, got, c.status)
+		}
+	}
+}
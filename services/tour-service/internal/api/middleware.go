@@ -1,31 +1,79 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
-	"strconv"
+	"os"
+	"strings"
+	"tour-service/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
-// getUserIDFromHeader izvlači User ID iz X-User-ID headera (postavljenog od API Gateway-a)
-func GetUserIDFromHeader(r *http.Request) (int, error) {
-	userIDStr := r.Header.Get("X-User-ID")
-	if userIDStr == "" {
-		return 0, nil
+// AdminRole je uloga administratora u JWT claimovima
+const AdminRole = "administrator"
+
+// AuthMiddleware proverava JWT iz "Authorization: Bearer" headera i upisuje ID ("userID") i ulogu ("userRole")
+// iz potpisanih claimova u context, odakle ih handleri citaju. X-User-* headeri se ne koriste jer frontend
+// salje zahteve direktno servisu i klijent moze sam da ih postavi. Bez ispravnog tokena context ostaje prazan.
+func AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := bearerToken(r); ok {
+			if claims, err := claimsFromToken(token); err == nil && claims.UserID > 0 {
+				ctx := context.WithValue(r.Context(), "userID", claims.UserID)
+				ctx = context.WithValue(ctx, "userRole", claims.Role)
+				r = r.WithContext(ctx)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bearerToken vraca token iz "Authorization: Bearer <token>" headera
+func bearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	token = strings.TrimSpace(token)
+	return token, ok && token != ""
+}
+
+// GetUserRole vraca ulogu korisnika iz proverenog tokena (prazno za anonimne zahteve)
+func GetUserRole(r *http.Request) string {
+	role, _ := r.Context().Value("userRole").(string)
+	return role
+}
+
+// getJWTSecret vraca isti JWT_SECRET kojim API Gateway proverava tokene
+func getJWTSecret() []byte {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return []byte("default-secret-change-this")
 	}
+	return []byte(secret)
+}
 
-	userID, err := strconv.Atoi(userIDStr)
+// UserIDFromToken validira JWT i vraca ID korisnika. Koristi se za live kanal, jer browser EventSource
+// ne moze da posalje headere pa token stize kao ?access_token=
+func UserIDFromToken(tokenString string) (uint, error) {
+	claims, err := claimsFromToken(tokenString)
 	if err != nil {
 		return 0, err
 	}
-
-	return userID, nil
+	return claims.UserID, nil
 }
 
-// GetUserRoleFromHeader izvlači User Role iz X-User-Role headera
-func GetUserRoleFromHeader(r *http.Request) string {
-	return r.Header.Get("X-User-Role")
-}
-
-// getUsernameFromHeader izvlači Username iz X-User-Username headera
-func GetUsernameFromHeader(r *http.Request) string {
-	return r.Header.Get("X-User-Username")
+// claimsFromToken proverava potpis i rok vazenja JWT-a i vraca njegove claimove
+func claimsFromToken(tokenString string) (*models.Claims, error) {
+	claims := &models.Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return getJWTSecret(), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
 }
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"tour-service/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func signedToken(t *testing.T, secret []byte, userID uint, role string) string {
+	t.Helper()
+	claims := models.Claims{
+		UserID: userID,
+		Role:   role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return token
+}
+
+// serveAuth propusta zahtev kroz AuthMiddleware i vraca identitet koji je handler video
+func serveAuth(r *http.Request) (userID uint, hasUser bool, role string) {
+	AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, hasUser = r.Context().Value("userID").(uint)
+		role = GetUserRole(r)
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	return
+}
+
+func TestAuthMiddlewareUsesVerifiedClaims(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/tours", nil)
+	r.Header.Set("Authorization", "Bearer "+signedToken(t, []byte("test-secret"), 7, AdminRole))
+	r.Header.Set("X-User-ID", "99")
+	r.Header.Set("X-User-Role", "tourist")
+
+	userID, hasUser, role := serveAuth(r)
+	if !hasUser || userID != 7 {
+		t.Errorf("expected user 7 from token, got %d (present=%v)", userID, hasUser)
+	}
+	if role != AdminRole {
+		t.Errorf("expected role %q from token, got %q", AdminRole, role)
+	}
+}
+
+func TestAuthMiddlewareIgnoresForgedIdentity(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	cases := map[string]string{
+		"headers only":  "",
+		"wrong secret":  "Bearer " + signedToken(t, []byte("other-secret"), 7, AdminRole),
+		"not a bearer":  "Basic dXNlcjpwYXNz",
+		"garbage token": "Bearer not-a-jwt",
+	}
+	for name, authorization := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/tours", nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		r.Header.Set("X-User-ID", "7")
+		r.Header.Set("X-User-Role", AdminRole)
+
+		if userID, hasUser, role := serveAuth(r); hasUser || role != "" {
+			t.Errorf("%s: expected anonymous request, got user %d with role %q", name, userID, role)
+		}
+	}
+}
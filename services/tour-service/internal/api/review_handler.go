@@ -2,7 +2,10 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"tour-service/internal/dto"
 	"tour-service/internal/service"
@@ -18,6 +21,21 @@ func NewReviewHandler(reviewService *service.ReviewService) *ReviewHandler {
 	return &ReviewHandler{reviewService: reviewService}
 }
 
+// reviewErrorStatus maps review errors to HTTP status codes
+func reviewErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrReviewNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrReviewNotAllowed), errors.Is(err, service.ErrReplyForbidden), errors.Is(err, service.ErrOwnReview):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrReviewExists), errors.Is(err, service.ErrAlreadyReported):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrPurchaseUnavailable):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadRequest
+}
+
 // CreateReview handles creating a new review
 func (h *ReviewHandler) CreateReview(w http.ResponseWriter, r *http.Request) {
 	// Get tourist ID from context (set by AuthMiddleware)
@@ -35,7 +53,7 @@ func (h *ReviewHandler) CreateReview(w http.ResponseWriter, r *http.Request) {
 
 	review, err := h.reviewService.CreateReview(touristID, req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), reviewErrorStatus(err))
 		return
 	}
 
@@ -44,7 +62,9 @@ func (h *ReviewHandler) CreateReview(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(review)
 }
 
-// GetReviewsByTour handles retrieving all reviews for a tour
+// GetReviewsByTour handles retrieving one page of reviews for a tour
+//
+//	?page=1&limit=10&sort=most_helpful&rating=4,5&withPhotos=true
 func (h *ReviewHandler) GetReviewsByTour(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	tourID, err := strconv.ParseUint(vars["tourId"], 10, 32)
@@ -53,7 +73,17 @@ func (h *ReviewHandler) GetReviewsByTour(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	reviews, err := h.reviewService.GetReviewsByTourID(uint(tourID))
+	query, err := parseReviewListQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reviews, err := h.reviewService.GetReviewsByTourID(uint(tourID), query)
+	if errors.Is(err, service.ErrInvalidQuery) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -105,7 +135,7 @@ func (h *ReviewHandler) UpdateReview(w http.ResponseWriter, r *http.Request) {
 
 	review, err := h.reviewService.UpdateReview(uint(reviewID), touristID, req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), reviewErrorStatus(err))
 		return
 	}
 
@@ -130,7 +160,7 @@ func (h *ReviewHandler) DeleteReview(w http.ResponseWriter, r *http.Request) {
 
 	err = h.reviewService.DeleteReview(uint(reviewID), touristID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), reviewErrorStatus(err))
 		return
 	}
 
@@ -154,3 +184,278 @@ func (h *ReviewHandler) GetMyReviews(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(reviews)
 }
+
+// parseReviewListQuery parses the paging, sorting and filter query parameters of the review listing
+func parseReviewListQuery(r *http.Request) (dto.ReviewListQuery, error) {
+	q := r.URL.Query()
+	query := dto.ReviewListQuery{
+		WithPhotos: q.Get("withPhotos") == "true",
+		Sort:       q.Get("sort"),
+	}
+
+	for _, raw := range splitQueryList(q.Get("rating")) {
+		rating, err := strconv.Atoi(raw)
+		if err != nil {
+			return query, fmt.Errorf("invalid rating: %s", raw)
+		}
+		query.Ratings = append(query.Ratings, rating)
+	}
+
+	err := parsePaging(q, &query.Page, &query.Limit)
+	return query, err
+}
+
+// parsePaging cita opcione celobrojne parametre page i limit; prazan parametar ostavlja nulu
+func parsePaging(q url.Values, page, limit *int) error {
+	ints := map[string]*int{
+		"page":  page,
+		"limit": limit,
+	}
+	for name, target := range ints {
+		raw := q.Get(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid %s", name)
+		}
+		*target = value
+	}
+	return nil
+}
+
+// ReplyToReview handles creating or editing the tour author's reply to a review
+func (h *ReviewHandler) ReplyToReview(w http.ResponseWriter, r *http.Request) {
+	authorID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	reviewID, err := strconv.ParseUint(vars["reviewId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.ReviewReplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	review, err := h.reviewService.ReplyToReview(uint(reviewID), authorID, req)
+	if err != nil {
+		http.Error(w, err.Error(), reviewErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
+
+// DeleteReply handles removing the tour author's reply from a review
+func (h *ReviewHandler) DeleteReply(w http.ResponseWriter, r *http.Request) {
+	authorID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	reviewID, err := strconv.ParseUint(vars["reviewId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.reviewService.DeleteReply(uint(reviewID), authorID); err != nil {
+		http.Error(w, err.Error(), reviewErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MarkReplyRead handles the reviewing tourist marking the author's reply as seen
+func (h *ReviewHandler) MarkReplyRead(w http.ResponseWriter, r *http.Request) {
+	touristID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	reviewID, err := strconv.ParseUint(vars["reviewId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.reviewService.MarkReplyRead(uint(reviewID), touristID); err != nil {
+		http.Error(w, err.Error(), reviewErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUnreadReplyCount handles retrieving how many of the tourist's reviews have an unseen author reply
+func (h *ReviewHandler) GetUnreadReplyCount(w http.ResponseWriter, r *http.Request) {
+	touristID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	count, err := h.reviewService.CountUnreadReplies(touristID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"unreadReplies": count})
+}
+
+// VoteReview handles a user's helpful / not helpful vote on a review
+func (h *ReviewHandler) VoteReview(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	reviewID, err := strconv.ParseUint(vars["reviewId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.ReviewVoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	review, err := h.reviewService.VoteReview(uint(reviewID), userID, req)
+	if err != nil {
+		http.Error(w, err.Error(), reviewErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
+
+// RemoveVote handles withdrawing a user's vote on a review
+func (h *ReviewHandler) RemoveVote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	reviewID, err := strconv.ParseUint(vars["reviewId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	review, err := h.reviewService.RemoveVote(uint(reviewID), userID)
+	if err != nil {
+		http.Error(w, err.Error(), reviewErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
+
+// ReportReview handles reporting an abusive review
+func (h *ReviewHandler) ReportReview(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	reviewID, err := strconv.ParseUint(vars["reviewId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.ReviewReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.reviewService.ReportReview(uint(reviewID), userID, req); err != nil {
+		http.Error(w, err.Error(), reviewErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// GetModerationQueue handles listing reviews waiting for moderation (administrators only)
+//
+//	?page=1&limit=10
+func (h *ReviewHandler) GetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	if GetUserRole(r) != AdminRole {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	var page, limit int
+	if err := parsePaging(r.URL.Query(), &page, &limit); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	queue, err := h.reviewService.GetModerationQueue(page, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(queue)
+}
+
+// ModerateReview handles an administrator approving or removing a reported review
+func (h *ReviewHandler) ModerateReview(w http.ResponseWriter, r *http.Request) {
+	if GetUserRole(r) != AdminRole {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	vars := mux.Vars(r)
+	reviewID, err := strconv.ParseUint(vars["reviewId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.ModerateReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	review, err := h.reviewService.ModerateReview(uint(reviewID), req)
+	if err != nil {
+		http.Error(w, err.Error(), reviewErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
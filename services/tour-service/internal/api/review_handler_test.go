@@ -0,0 +1,16 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParsePagingRejectsNonNumericValues(t *testing.T) {
+	var page, limit int
+	if err := parsePaging(url.Values{"page": {"abc"}}, &page, &limit); err == nil {
+		t.Error("expected an error for a non-numeric page")
+	}
+	if err := parsePaging(url.Values{"page": {"3"}, "limit": {"25"}}, &page, &limit); err != nil || page != 3 || limit != 25 {
+		t.Errorf("expected page 3 and limit 25, got %d, %d (%v)", page, limit, err)
+	}
+}
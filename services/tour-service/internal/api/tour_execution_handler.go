@@ -2,8 +2,13 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
+	"tour-service/internal/dto"
+	"tour-service/internal/models"
 	"tour-service/internal/service"
 
 	"github.com/gorilla/mux"
@@ -17,6 +22,19 @@ func NewTourExecutionHandler(service *service.TourExecutionService) *TourExecuti
 	return &TourExecutionHandler{service: service}
 }
 
+// executionErrorStatus mapira greske izvrsavanja na HTTP status
+func executionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrExecutionNotFound), errors.Is(err, service.ErrTourNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrNotExecutionOwner), errors.Is(err, service.ErrAnalyticsForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrExecutionConflict):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
 func (h *TourExecutionHandler) StartTour(w http.ResponseWriter, r *http.Request) {
 	touristID, _ := r.Context().Value("userID").(uint)
 	
@@ -40,6 +58,12 @@ func (h *TourExecutionHandler) StartTour(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *TourExecutionHandler) CheckPosition(w http.ResponseWriter, r *http.Request) {
+    touristID, ok := r.Context().Value("userID").(uint)
+    if !ok {
+        http.Error(w, "Unauthorized", http.StatusUnauthorized)
+        return
+    }
+
     vars := mux.Vars(r)
     executionID, _ := strconv.ParseUint(vars["executionId"], 10, 32)
 
@@ -49,22 +73,29 @@ func (h *TourExecutionHandler) CheckPosition(w http.ResponseWriter, r *http.Requ
     }
     json.NewDecoder(r.Body).Decode(&req)
 
-    completed, err := h.service.CheckPosition(uint(executionID), req.CurrentLat, req.CurrentLng)
+    result, err := h.service.CheckPosition(uint(executionID), touristID, req.CurrentLat, req.CurrentLng)
     if err != nil {
-        http.Error(w, err.Error(), http.StatusBadRequest)
+        http.Error(w, err.Error(), executionErrorStatus(err))
         return
     }
 
-    json.NewEncoder(w).Encode(completed)
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(result)
 }
 
 func (h *TourExecutionHandler) CompleteTour(w http.ResponseWriter, r *http.Request) {
+	touristID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	vars := mux.Vars(r)
 	executionID, _ := strconv.ParseUint(vars["executionId"], 10, 32)
 
-	err := h.service.CompleteTour(uint(executionID))
+	err := h.service.CompleteTour(uint(executionID), touristID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), executionErrorStatus(err))
 		return
 	}
 
@@ -72,12 +103,18 @@ func (h *TourExecutionHandler) CompleteTour(w http.ResponseWriter, r *http.Reque
 }
 
 func (h *TourExecutionHandler) AbandonTour(w http.ResponseWriter, r *http.Request) {
+	touristID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	vars := mux.Vars(r)
 	executionID, _ := strconv.ParseUint(vars["executionId"], 10, 32)
 
-	err := h.service.AbandonTour(uint(executionID))
+	err := h.service.AbandonTour(uint(executionID), touristID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), executionErrorStatus(err))
 		return
 	}
 
@@ -102,12 +139,22 @@ func (h *TourExecutionHandler) GetActiveExecution(w http.ResponseWriter, r *http
 }
 
 func (h *TourExecutionHandler) GetExecutionDetails(w http.ResponseWriter, r *http.Request) {
+    userID, ok := r.Context().Value("userID").(uint)
+    if !ok {
+        http.Error(w, "Unauthorized", http.StatusUnauthorized)
+        return
+    }
+
     vars := mux.Vars(r)
     executionID, _ := strconv.ParseUint(vars["executionId"], 10, 32)
 
-    execution, err := h.service.GetExecutionDetails(uint(executionID))
+    execution, err := h.service.GetExecutionDetails(uint(executionID), userID, GetUserRole(r) == AdminRole)
     if err != nil {
-        http.Error(w, err.Error(), http.StatusNotFound)
+        status := executionErrorStatus(err)
+        if status == http.StatusBadRequest {
+            status = http.StatusInternalServerError
+        }
+        http.Error(w, err.Error(), status)
         return
     }
 
@@ -116,15 +163,294 @@ func (h *TourExecutionHandler) GetExecutionDetails(w http.ResponseWriter, r *htt
 }
 
 func (h *TourExecutionHandler) GetExecutionsByTour(w http.ResponseWriter, r *http.Request) {
+    userID, ok := r.Context().Value("userID").(uint)
+    if !ok {
+        http.Error(w, "Unauthorized", http.StatusUnauthorized)
+        return
+    }
+
     vars := mux.Vars(r)
     tourID, _ := strconv.ParseUint(vars["tourId"], 10, 32)
 
-    executions, err := h.service.GetExecutionsByTour(uint(tourID))
+    executions, err := h.service.GetExecutionsByTour(uint(tourID), userID, GetUserRole(r) == AdminRole)
     if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+        status := http.StatusInternalServerError
+        if errors.Is(err, service.ErrTourNotFound) {
+            status = http.StatusNotFound
+        }
+        http.Error(w, err.Error(), status)
         return
     }
 
     w.Header().Set("Content-Type", "application/json")
     json.NewEncoder(w).Encode(executions)
-}
\ No newline at end of file
+}
+
+// vraca predjenu putanju izvrsavanja kao GeoJSON (podrazumevano) ili GPX
+func (h *TourExecutionHandler) GetExecutionTrail(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	executionID, err := strconv.ParseUint(vars["executionId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid execution ID", http.StatusBadRequest)
+		return
+	}
+
+	format := service.RouteFormatFromAccept(r.Header.Get("Accept"))
+	if raw := r.URL.Query().Get("format"); raw != "" {
+		if format, err = service.ParseRouteFormat(raw); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	body, err := h.service.GetTrail(uint(executionID), userID, GetUserRole(r) == AdminRole, format)
+	if err != nil {
+		http.Error(w, err.Error(), executionErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"execution-%d-trail.%s\"", executionID, format))
+	w.Write(body)
+}
+
+// vraca predjeni put, vreme kretanja i prosecnu brzinu izvrsavanja
+func (h *TourExecutionHandler) GetExecutionTrailStats(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	executionID, err := strconv.ParseUint(vars["executionId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid execution ID", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.service.GetTrailStats(uint(executionID), userID, GetUserRole(r) == AdminRole)
+	if err != nil {
+		http.Error(w, err.Error(), executionErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+const sseHeartbeatInterval = 15 * time.Second
+
+// StreamExecutionEvents je live kanal izvrsavanja (Server-Sent Events). Klijent salje pozicije preko
+// POST /executions/{id}/check-position, a ovde dobija dogadjaje: keypoint_reached, next_keypoint,
+// complete_eligible, completed i abandoned. Pri povezivanju stize "state" sa trenutnim stanjem, osim ako
+// klijent nastavlja od Last-Event-ID koji je server jos zapamtio - tada stizu samo propusteni dogadjaji.
+func (h *TourExecutionHandler) StreamExecutionEvents(w http.ResponseWriter, r *http.Request) {
+	touristID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		token := r.URL.Query().Get("access_token")
+		if token == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		userID, err := UserIDFromToken(token)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		touristID = userID
+	}
+
+	vars := mux.Vars(r)
+	executionID, err := strconv.ParseUint(vars["executionId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid execution ID", http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	lastEventID, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+	subscription, err := h.service.SubscribeExecution(uint(executionID), touristID, lastEventID)
+	if err != nil {
+		http.Error(w, err.Error(), executionErrorStatus(err))
+		return
+	}
+	defer subscription.Cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if subscription.State != nil {
+		writeSSE(w, 0, service.EventState, subscription.State)
+		if subscription.State.Execution.Status != models.ExecutionStarted {
+			flusher.Flush()
+			return
+		}
+	}
+	for _, event := range subscription.Missed {
+		writeSSE(w, event.ID, event.Type, event)
+		if event.IsTerminal() {
+			flusher.Flush()
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-subscription.Events:
+			writeSSE(w, event.ID, event.Type, event)
+			flusher.Flush()
+			if event.IsTerminal() {
+				return
+			}
+		case <-heartbeat.C:
+			// izvrsavanje je mozda zavrsila druga instanca (npr. worker za neaktivna izvrsavanja)
+			status, err := h.service.ExecutionStatus(uint(executionID))
+			if err == nil && status != models.ExecutionStarted {
+				eventType := service.EventCompleted
+				if status == models.ExecutionAbandoned {
+					eventType = service.EventAbandoned
+				}
+				writeSSE(w, 0, eventType, map[string]interface{}{"executionId": executionID, "status": status})
+				flusher.Flush()
+				return
+			}
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// writeSSE pise jedan dogadjaj u SSE formatu; id 0 znaci dogadjaj bez ID-a (ne pomera Last-Event-ID)
+func writeSSE(w http.ResponseWriter, id uint64, eventType string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	if id > 0 {
+		fmt.Fprintf(w, "id: %d\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, payload)
+}
+
+// vraca statistiku izvrsavanja ture (samo autor ture i administrator)
+func (h *TourExecutionHandler) GetTourAnalytics(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	tourID, err := strconv.ParseUint(vars["tourId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	query := dto.TourAnalyticsQuery{Interval: r.URL.Query().Get("interval")}
+	for param, target := range map[string]**time.Time{"from": &query.From, "to": &query.To} {
+		raw := r.URL.Query().Get(param)
+		if raw == "" {
+			continue
+		}
+		t, err := parseAnalyticsTime(raw)
+		if err != nil {
+			http.Error(w, "Invalid "+param+" (expected RFC3339 or YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		*target = &t
+	}
+
+	analytics, err := h.service.GetTourAnalytics(uint(tourID), userID, GetUserRole(r) == AdminRole, query)
+	if err != nil {
+		http.Error(w, err.Error(), executionErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(analytics)
+}
+
+func parseAnalyticsTime(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", raw)
+}
+
+// vraca tajni sadrzaj keypointa do kojih je turista stigao u izvrsavanju
+func (h *TourExecutionHandler) GetRevealedSecrets(w http.ResponseWriter, r *http.Request) {
+	touristID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	executionID, err := strconv.ParseUint(vars["executionId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid execution ID", http.StatusBadRequest)
+		return
+	}
+
+	secrets, err := h.service.GetRevealedSecrets(uint(executionID), touristID)
+	if err != nil {
+		http.Error(w, err.Error(), executionErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(secrets)
+}
+
+// prima pozicije snimljene offline i ponavlja ih po vremenu snimanja
+func (h *TourExecutionHandler) SyncPositions(w http.ResponseWriter, r *http.Request) {
+	touristID, ok := r.Context().Value("userID").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	executionID, err := strconv.ParseUint(vars["executionId"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid execution ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.SyncPositionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.SyncPositions(uint(executionID), touristID, req)
+	if err != nil {
+		http.Error(w, err.Error(), executionErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
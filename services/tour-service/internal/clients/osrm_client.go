@@ -0,0 +1,87 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"tour-service/internal/interfaces"
+	"tour-service/internal/models"
+)
+
+// osrmProfiles mapira tip prevoza na OSRM profil
+var osrmProfiles = map[models.TransportType]string{
+	models.Walking: "foot",
+	models.Bicycle: "bike",
+	models.Car:     "car",
+}
+
+// OSRMRoutingProvider racuna rute preko OSRM-kompatibilnog HTTP API-ja (/route/v1/{profile}/{coordinates})
+type OSRMRoutingProvider struct {
+	Client  *http.Client
+	BaseURL string // npr. "http://osrm:5000"
+}
+
+func NewOSRMRoutingProvider(baseURL string) interfaces.RoutingProvider {
+	return &OSRMRoutingProvider{
+		Client:  &http.Client{Timeout: 5 * time.Second},
+		BaseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+func (c *OSRMRoutingProvider) Route(transport models.TransportType, points []interfaces.Coordinate) (*interfaces.RouteEstimate, error) {
+	if len(points) < 2 {
+		return &interfaces.RouteEstimate{}, nil
+	}
+
+	profile, ok := osrmProfiles[transport]
+	if !ok {
+		return nil, fmt.Errorf("unsupported transport type: %s", transport)
+	}
+
+	coordinates := make([]string, len(points))
+	for i, p := range points {
+		coordinates[i] = fmt.Sprintf("%.6f,%.6f", p.Longitude, p.Latitude)
+	}
+	reqURL := fmt.Sprintf("%s/route/v1/%s/%s?overview=false", c.BaseURL, profile, strings.Join(coordinates, ";"))
+
+	resp, err := c.Client.Get(reqURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call routing service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Routes  []struct {
+			Distance float64 `json:"distance"` // metri
+			Duration float64 `json:"duration"` // sekunde
+			Legs     []struct {
+				Distance float64 `json:"distance"`
+			} `json:"legs"`
+		} `json:"routes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode routing response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || result.Code != "Ok" || len(result.Routes) == 0 {
+		return nil, fmt.Errorf("routing service returned %d %s: %s", resp.StatusCode, result.Code, result.Message)
+	}
+
+	route := result.Routes[0]
+	if len(route.Legs) != len(points)-1 {
+		return nil, fmt.Errorf("routing service returned %d legs for %d points", len(route.Legs), len(points))
+	}
+
+	estimate := &interfaces.RouteEstimate{
+		DistanceKm:  route.Distance / 1000,
+		DurationMin: int(math.Ceil(route.Duration / 60)),
+	}
+	for _, leg := range route.Legs {
+		estimate.LegDistancesKm = append(estimate.LegDistancesKm, leg.Distance/1000)
+	}
+	return estimate, nil
+}
@@ -8,21 +8,41 @@ import (
 	"gorm.io/gorm"
 )
 
-func InitDB() *gorm.DB {
+// Connect otvara konekciju ka bazi bez migracije, za alate koji moraju da rade i nad starom semom
+func Connect() *gorm.DB {
 	dsn := "host=tour-db user=postgres password=password dbname=tours port=5432 sslmode=disable"
 
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
 		log.Fatal("!!! FAILED TO CONNECT TO DATABASE:", err)
 	}
+	return db
+}
+
+func InitDB() *gorm.DB {
+	db := Connect()
 
 	log.Println("Database connection successful. Running migration...")
 
+	// unique index (tour_id, tourist_id) ne moze da se napravi dok postoje duple recenzije,
+	// a njih brise samo rucno pokrenut dedupe-reviews
+	duplicates, err := FindDuplicateReviews(db)
+	if err != nil {
+		log.Fatal("!!! FAILED TO CHECK FOR DUPLICATE REVIEWS:", err)
+	}
+	if len(duplicates) > 0 {
+		log.Fatalf("!!! FOUND %d DUPLICATE REVIEWS (same tourist and tour), the unique review index cannot be created. "+
+			"Inspect them with ./dedupe-reviews and remove them with ./dedupe-reviews -apply", len(duplicates))
+	}
+
 	// --- KLJUČNA IZMENA JE OVDE ---
 	// Eksplicitno proveravamo da li je migracija uspela.
 	// Ako ne uspe, aplikacija će se srušiti i ispisati tačnu grešku.
 	err = db.AutoMigrate(&models.Tour{}, &models.KeyPoint{}, &models.TourDuration{}, 
-		&models.Review{}, &models.TourExecution{} )
+		&models.Review{}, &models.TourExecution{}, &models.TourPriceHistory{},
+		&models.TourVersion{}, &models.ExecutionTrackPoint{},
+		&models.KeyPointSecret{}, &models.UserBadge{},
+		&models.ReviewVote{}, &models.ReviewReport{} )
 	if err != nil {
 		log.Fatal("!!! FAILED TO MIGRATE DATABASE:", err)
 	}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"time"
+	"tour-service/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// DuplicateReview je recenzija koja se brise jer isti turista ima noviju recenziju iste ture
+type DuplicateReview struct {
+	ID        uint
+	TourID    uint
+	TouristID uint
+	Rating    int
+	UpdatedAt *time.Time
+	KeptID    uint // recenzija koja ostaje
+}
+
+// duplicateReviewsQuery rangira recenzije istog turiste za istu turu od najskorije izmenjene.
+// Recenzija bez updated_at je najstarija, a pri istom vremenu ostaje ona sa vecim ID-jem.
+const duplicateReviewsQuery = `SELECT id, tour_id, tourist_id, rating, updated_at, kept_id FROM (
+		SELECT id, tour_id, tourist_id, rating, updated_at,
+			FIRST_VALUE(id) OVER w AS kept_id,
+			ROW_NUMBER() OVER w AS row_rank
+		FROM reviews
+		WINDOW w AS (PARTITION BY tour_id, tourist_id ORDER BY updated_at DESC NULLS LAST, id DESC)
+	) ranked
+	WHERE row_rank > 1
+	ORDER BY tour_id, tourist_id, id`
+
+// FindDuplicateReviews vraca recenzije koje bi RemoveDuplicateReviews obrisao, bez izmena u bazi
+func FindDuplicateReviews(db *gorm.DB) ([]DuplicateReview, error) {
+	duplicates := []DuplicateReview{}
+	if !db.Migrator().HasTable(&models.Review{}) {
+		return duplicates, nil
+	}
+	err := db.Raw(duplicateReviewsQuery).Scan(&duplicates).Error
+	return duplicates, err
+}
+
+// RemoveDuplicateReviews ostavlja samo poslednju izmenjenu recenziju turiste za turu i brise
+// glasove i prijave obrisanih recenzija. Vraca obrisane recenzije.
+func RemoveDuplicateReviews(db *gorm.DB) ([]DuplicateReview, error) {
+	var removed []DuplicateReview
+	err := db.Transaction(func(tx *gorm.DB) error {
+		duplicates, err := FindDuplicateReviews(tx)
+		if err != nil || len(duplicates) == 0 {
+			removed = duplicates
+			return err
+		}
+
+		ids := make([]uint, len(duplicates))
+		for i, d := range duplicates {
+			ids[i] = d.ID
+		}
+		if tx.Migrator().HasTable(&models.ReviewVote{}) {
+			if err := tx.Where("review_id IN ?", ids).Delete(&models.ReviewVote{}).Error; err != nil {
+				return err
+			}
+		}
+		if tx.Migrator().HasTable(&models.ReviewReport{}) {
+			if err := tx.Where("review_id IN ?", ids).Delete(&models.ReviewReport{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("id IN ?", ids).Delete(&models.Review{}).Error; err != nil {
+			return err
+		}
+		removed = duplicates
+		return nil
+	})
+	return removed, err
+}
@@ -0,0 +1,41 @@
+package dto
+
+import "time"
+
+// TourAnalyticsQuery holds the period and bucket size for tour execution analytics
+type TourAnalyticsQuery struct {
+	Interval string     // "day", "week" ili "month"
+	From     *time.Time // izvrsavanja zapoceta od (ukljucivo)
+	To       *time.Time // izvrsavanja zapoceta pre (iskljucivo)
+}
+
+// TourAnalyticsResponse DTO for execution analytics of one tour
+type TourAnalyticsResponse struct {
+	TourID                  uint                   `json:"tourId"`
+	Interval                string                 `json:"interval"`
+	Started                 int                    `json:"started"`
+	Completed               int                    `json:"completed"`
+	Abandoned               int                    `json:"abandoned"`
+	InProgress              int                    `json:"inProgress"`
+	CompletionRate          float64                `json:"completionRate"`                    // Udeo zavrsenih medju izvrsavanjima koja su se okoncala (0-1)
+	MedianCompletionSeconds *int64                 `json:"medianCompletionSeconds,omitempty"` // nil dok nema zavrsenih
+	Timeline                []ExecutionPeriodStats `json:"timeline"`
+	Funnel                  []FunnelStep           `json:"funnel"`
+}
+
+// ExecutionPeriodStats counts executions started, completed and abandoned in one period
+type ExecutionPeriodStats struct {
+	PeriodStart time.Time `json:"periodStart"`
+	Started     int       `json:"started"`
+	Completed   int       `json:"completed"`
+	Abandoned   int       `json:"abandoned"`
+}
+
+// FunnelStep is the number of executions that reached the key point at a given position of the tour
+type FunnelStep struct {
+	Order   int     `json:"order"`
+	Name    string  `json:"name"`
+	Reached int     `json:"reached"`
+	Percent float64 `json:"percent"` // Udeo svih zapocetih izvrsavanja (0-100)
+	DropOff int     `json:"dropOff"` // Koliko ih je stiglo do prethodnog koraka, a do ovog ne; 0 ako je ovaj dostignut cesce (FreeOrder)
+}
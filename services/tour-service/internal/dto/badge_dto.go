@@ -0,0 +1,22 @@
+package dto
+
+import "time"
+
+// BadgeResponse DTO for a badge a user has earned
+type BadgeResponse struct {
+	Code        string    `json:"code"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	EarnedAt    time.Time `json:"earnedAt"`
+}
+
+// BadgeProgressResponse DTO for a badge from the catalog with the user's progress towards it
+type BadgeProgressResponse struct {
+	Code        string     `json:"code"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Current     float64    `json:"current"`
+	Target      float64    `json:"target"`
+	Earned      bool       `json:"earned"`
+	EarnedAt    *time.Time `json:"earnedAt,omitempty"`
+}
@@ -2,34 +2,51 @@ package dto
 
 // CreateKeyPointRequest DTO for creating a key point
 type CreateKeyPointRequest struct {
-	Name        string  `json:"name" binding:"required"`
-	Description string  `json:"description" binding:"required"`
-	Latitude    float64 `json:"latitude" binding:"required"`
-	Longitude   float64 `json:"longitude" binding:"required"`
-	Image       string  `json:"image"`
-	Order       int     `json:"order" binding:"required"`
+	Name          string                 `json:"name" binding:"required"`
+	Description   string                 `json:"description" binding:"required"`
+	Latitude      float64                `json:"latitude" binding:"required"`
+	Longitude     float64                `json:"longitude" binding:"required"`
+	Image         string                 `json:"image"`
+	Order         int                    `json:"order" binding:"required"`
+	ArrivalRadius float64                `json:"arrivalRadius"` // u metrima, 0 znaci podrazumevani radius
+	Secret        *KeyPointSecretRequest `json:"secret,omitempty"`
 }
 
 // UpdateKeyPointRequest DTO for updating a key point
 type UpdateKeyPointRequest struct {
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Latitude    float64 `json:"latitude"`
-	Longitude   float64 `json:"longitude"`
-	Image       string  `json:"image"`
-	Order       int     `json:"order"`
+	Name          string                 `json:"name"`
+	Description   string                 `json:"description"`
+	Latitude      float64                `json:"latitude"`
+	Longitude     float64                `json:"longitude"`
+	Image         string                 `json:"image"`
+	Order         int                    `json:"order"`
+	ArrivalRadius float64                `json:"arrivalRadius"`
+	Secret        *KeyPointSecretRequest `json:"secret,omitempty"` // nil ne menja tajnu, prazna tajna je brise
+}
+
+// KeyPointSecretRequest DTO for the hidden content revealed when a tourist reaches the key point
+type KeyPointSecretRequest struct {
+	Text     string   `json:"text"`
+	Images   []string `json:"images"`
+	AudioURL string   `json:"audioUrl"`
+}
+
+// ReorderKeyPointsRequest DTO for reordering all key points of a tour
+type ReorderKeyPointsRequest struct {
+	KeyPointIDs []uint `json:"keyPointIds"` // Svi keypointi ture u novom redosledu
 }
 
 // KeyPointResponse DTO for key point response
 type KeyPointResponse struct {
-	ID          uint    `json:"id"`
-	TourID      uint    `json:"tourId"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Latitude    float64 `json:"latitude"`
-	Longitude   float64 `json:"longitude"`
-	Image       string  `json:"image"`
-	Order       int     `json:"order"`
-	CreatedAt   string  `json:"createdAt"`
-	UpdatedAt   string  `json:"updatedAt"`
-}
\ No newline at end of file
+	ID            uint    `json:"id"`
+	TourID        uint    `json:"tourId"`
+	Name          string  `json:"name"`
+	Description   string  `json:"description"`
+	Latitude      float64 `json:"latitude"`
+	Longitude     float64 `json:"longitude"`
+	Image         string  `json:"image"`
+	Order         int     `json:"order"`
+	ArrivalRadius float64 `json:"arrivalRadius"`
+	CreatedAt     string  `json:"createdAt"`
+	UpdatedAt     string  `json:"updatedAt"`
+}
@@ -1,6 +1,9 @@
 package dto
 
-import "time"
+import (
+	"time"
+	"tour-service/internal/models"
+)
 
 // CreateReviewRequest represents the payload for creating a review
 type CreateReviewRequest struct {
@@ -33,3 +36,71 @@ type ReviewResponse struct {
 	CreatedAt       time.Time `json:"createdAt"`
 	UpdatedAt       time.Time `json:"updatedAt"`
 }
+
+// ReviewReplyRequest represents the payload for the tour author's reply to a review
+type ReviewReplyRequest struct {
+	Text string `json:"text"`
+}
+
+// ReviewListQuery holds the filters, sorting and paging for the reviews of a tour
+type ReviewListQuery struct {
+	Ratings    []int  // samo recenzije sa ovim ocenama, prazno = sve
+	WithPhotos bool   // samo recenzije sa bar jednom slikom
+	Sort       string // "newest", "highest", "lowest", "most_helpful"
+	Page       int
+	Limit      int
+}
+
+// PagedReviewsResponse is one page of reviews together with the total number of matches
+type PagedReviewsResponse struct {
+	Results    []models.Review `json:"results"`
+	TotalCount int64           `json:"totalCount"`
+	Page       int             `json:"page"`
+	Limit      int             `json:"limit"`
+}
+
+// ReviewStatsResponse holds the rating statistics of a tour
+type ReviewStatsResponse struct {
+	AverageRating float64       `json:"averageRating"`
+	ReviewCount   int64         `json:"reviewCount"`
+	Histogram     map[int]int64 `json:"histogram"` // broj recenzija po oceni 1-5
+	Trend         []RatingTrend `json:"trend"`
+}
+
+// RatingTrend compares the ratings of the last Days days with the ratings before them
+type RatingTrend struct {
+	Days          int      `json:"days"`
+	ReviewCount   int64    `json:"reviewCount"`
+	AverageRating float64  `json:"averageRating"`
+	Change        *float64 `json:"change,omitempty"` // prosek u periodu minus prosek pre njega, nil ako jedan od njih nema recenzija
+}
+
+// ReviewVoteRequest represents a user's vote on whether a review is helpful
+type ReviewVoteRequest struct {
+	Helpful bool `json:"helpful"`
+}
+
+// ReviewReportRequest represents a report of an abusive review
+type ReviewReportRequest struct {
+	Reason  string `json:"reason"` // "spam", "offensive", "fake", "off_topic" ili "other"
+	Details string `json:"details"`
+}
+
+// ModerateReviewRequest represents an administrator's decision on a reported review
+type ModerateReviewRequest struct {
+	Action string `json:"action"` // "approve" ili "remove"
+}
+
+// ModerationQueueItem is a review waiting for moderation with its open reports
+type ModerationQueueItem struct {
+	Review  models.Review         `json:"review"`
+	Reports []models.ReviewReport `json:"reports"`
+}
+
+// PagedModerationQueueResponse is one page of the review moderation queue
+type PagedModerationQueueResponse struct {
+	Results    []ModerationQueueItem `json:"results"`
+	TotalCount int64                 `json:"totalCount"`
+	Page       int                   `json:"page"`
+	Limit      int                   `json:"limit"`
+}
@@ -1,10 +1,69 @@
 package dto
 
+import "tour-service/internal/models"
 
 type CreateTourRequest struct {
 	Name        string   `json:"name"`
 	Description string   `json:"description"`
 	Difficulty  string   `json:"difficulty"`
 	Tags        []string `json:"tags"`
+	Price       float64  `json:"price"`
+	CompletionMode string `json:"completionMode"`
 	KeyPoints   []CreateKeyPointRequest  `json:"keyPoints"`
-}
\ No newline at end of file
+}
+
+// ImportTourRequest holds the form fields sent together with an uploaded GPX/GeoJSON file
+type ImportTourRequest struct {
+	Name         string
+	Description  string
+	Difficulty   string
+	Tags         []string
+	MaxKeyPoints int
+}
+
+// CloneTourRequest DTO for duplicating a tour as a new draft
+type CloneTourRequest struct {
+	Name string `json:"name"`
+}
+
+// UpdateCompletionModeRequest DTO for choosing how the key points of a tour are completed
+type UpdateCompletionModeRequest struct {
+	CompletionMode string `json:"completionMode"`
+}
+
+// UpdatePriceRequest DTO for changing the price of a tour
+type UpdatePriceRequest struct {
+	Price float64 `json:"price"`
+}
+
+// TourSearchQuery holds the filters, sorting and paging for the published tour catalogue
+type TourSearchQuery struct {
+	Difficulties  []string
+	Tags          []string
+	MatchAllTags  bool
+	MinPrice      *float64
+	MaxPrice      *float64
+	MinDistance   *float64
+	MaxDistance   *float64
+	MinRating     *float64
+	TransportType string
+	Text          string
+	Sort          string // "newest", "price_asc", "price_desc", "distance_asc", "distance_desc", "rating"
+	Page          int
+	Limit         int
+}
+
+// PagedToursResponse is one page of tours together with the total number of matches
+type PagedToursResponse struct {
+	Results    []models.Tour `json:"results"`
+	TotalCount int64         `json:"totalCount"`
+	Page       int           `json:"page"`
+	Limit      int           `json:"limit"`
+}
+
+// NearbyTourResponse is a published tour found near a location with the distance to its closest key point
+type NearbyTourResponse struct {
+	Tour              models.Tour `json:"tour"`
+	DistanceKm        float64     `json:"distanceKm"`
+	NearestKeyPointID uint        `json:"nearestKeyPointId"`
+}
@@ -0,0 +1,74 @@
+package dto
+
+import (
+	"time"
+	"tour-service/internal/models"
+)
+
+// CheckPositionResponse DTO for the result of a position check during tour execution
+type CheckPositionResponse struct {
+	NewlyCompleted     []int              `json:"newlyCompleted"`         // Keypointi zavrseni ovom proverom
+	CompletedKeyPoints []int64            `json:"completedKeyPoints"`     // Svi zavrseni keypointi izvrsavanja
+	NextKeyPoint       *models.KeyPoint   `json:"nextKeyPoint,omitempty"` // nil kada su svi keypointi zavrseni
+	DistanceToNextKm   float64            `json:"distanceToNextKm"`
+	CompletionMode     string             `json:"completionMode"`
+	Revealed           []RevealedKeyPoint `json:"revealed,omitempty"` // Tajni sadrzaj keypointa zavrsenih ovom proverom
+}
+
+// SyncPositionsRequest DTO for positions recorded while the tourist was offline
+type SyncPositionsRequest struct {
+	Positions []SyncedPosition `json:"positions"`
+}
+
+// SyncedPosition is one position recorded offline
+type SyncedPosition struct {
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+// SyncPositionsResponse DTO for the result of replaying an offline batch
+type SyncPositionsResponse struct {
+	CheckPositionResponse
+	Accepted        int                `json:"accepted"`
+	Duplicates      int                `json:"duplicates"` // Pozicije koje su vec bile primljene (ponovljen batch)
+	Rejected        []RejectedPosition `json:"rejected"`
+	ExecutionStatus string             `json:"executionStatus"`
+}
+
+// RejectedPosition is a position from a batch that could not be replayed
+type RejectedPosition struct {
+	Index      int       `json:"index"`
+	RecordedAt time.Time `json:"recordedAt"`
+	Reason     string    `json:"reason"`
+}
+
+// RevealedKeyPoint DTO for the secret content of a key point the tourist has reached
+type RevealedKeyPoint struct {
+	KeyPointID uint     `json:"keyPointId"`
+	Name       string   `json:"name"`
+	Order      int      `json:"order"`
+	Text       string   `json:"text"`
+	Images     []string `json:"images"`
+	AudioURL   string   `json:"audioUrl"`
+}
+
+// ExecutionStateResponse DTO for the current state of an execution sent when a live channel (re)connects
+type ExecutionStateResponse struct {
+	Execution        *models.TourExecution `json:"execution"`
+	NextKeyPoint     *models.KeyPoint      `json:"nextKeyPoint,omitempty"`
+	DistanceToNextKm *float64              `json:"distanceToNextKm,omitempty"` // od poslednje prijavljene pozicije
+	CompleteEligible bool                  `json:"completeEligible"`
+}
+
+// TrailStatsResponse DTO for statistics derived from the recorded GPS trail of an execution
+type TrailStatsResponse struct {
+	ExecutionID       uint       `json:"executionId"`
+	PointCount        int        `json:"pointCount"`
+	DistanceKm        float64    `json:"distanceKm"`        // Predjeni put, bez GPS suma dok turista stoji
+	MovingTimeSeconds int64      `json:"movingTimeSeconds"` // Vreme provedeno u kretanju
+	ElapsedSeconds    int64      `json:"elapsedSeconds"`    // Od prve do poslednje tacke
+	AverageSpeedKmh   float64    `json:"averageSpeedKmh"`   // Prosecna brzina u kretanju
+	StartedAt         *time.Time `json:"startedAt,omitempty"`
+	EndedAt           *time.Time `json:"endedAt,omitempty"`
+}
@@ -0,0 +1,22 @@
+package interfaces
+
+import "tour-service/internal/models"
+
+// Coordinate is a single point on a route
+type Coordinate struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// RouteEstimate is the result of routing through an ordered list of points
+type RouteEstimate struct {
+	LegDistancesKm []float64 // LegDistancesKm[i] je distanca od tacke i do tacke i+1
+	DistanceKm     float64
+	DurationMin    int
+	Estimated      bool // procena po vazdusnoj liniji, a ne ruta po putevima
+}
+
+// RoutingProvider estimates distance and travel time between ordered points for a transport type.
+type RoutingProvider interface {
+	Route(transport models.TransportType, points []Coordinate) (*RouteEstimate, error)
+}
@@ -16,7 +16,8 @@ type TourDuration struct {
 	ID            uint          `json:"id" gorm:"primaryKey"`
 	TourID        uint          `json:"tourId"`
 	TransportType TransportType `json:"transportType"`
-	DurationMin   int           `json:"durationMin"` // Duration in minutes
+	DurationMin   int           `json:"durationMin"`                    // Duration in minutes
+	Suggested     bool          `json:"suggested" gorm:"default:false"` // Automatically estimated by the routing provider
 	CreatedAt     time.Time     `json:"createdAt"`
 	UpdatedAt     time.Time     `json:"updatedAt"`
 }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// ExecutionTrackPoint is one GPS position reported by the tourist during a tour execution
+type ExecutionTrackPoint struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	ExecutionID uint      `json:"executionId" gorm:"index:idx_track_points_execution,priority:1;not null"`
+	Latitude    float64   `json:"latitude"`
+	Longitude   float64   `json:"longitude"`
+	RecordedAt  time.Time `json:"recordedAt" gorm:"index:idx_track_points_execution,priority:2"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func (ExecutionTrackPoint) TableName() string { return "execution_track_points" }
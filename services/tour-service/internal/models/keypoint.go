@@ -6,17 +6,21 @@ import (
 
 // KeyPoint struct represents a key point in a tour
 type KeyPoint struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	TourID      uint      `json:"tourId"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Latitude    float64   `json:"latitude"`
-	Longitude   float64   `json:"longitude"`
-	Address     string    `json:"address"` // Geocoded address
-	Image       string    `json:"image"`   // URL or path to image
-	Order       int       `json:"order"`   // Order in the tour sequence
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	TourID        uint      `json:"tourId"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	Latitude      float64   `json:"latitude" gorm:"index:idx_key_points_location,priority:1"`
+	Longitude     float64   `json:"longitude" gorm:"index:idx_key_points_location,priority:2"`
+	Address       string    `json:"address"`                         // Geocoded address
+	Image         string    `json:"image"`                           // URL or path to image
+	Order         int       `json:"order"`                           // Order in the tour sequence
+	LegDistance   float64   `json:"legDistance"`                     // Distance in km from the previous key point along the route
+	ArrivalRadius float64   `json:"arrivalRadius" gorm:"default:50"` // Radius in meters within which the key point counts as reached
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+	// Tajni sadrzaj se ucitava samo eksplicitno (Preload), pa ga obicni upiti nikad ne vracaju
+	Secret *KeyPointSecret `json:"secret,omitempty" gorm:"foreignKey:KeyPointID"`
 }
 
 func (KeyPoint) TableName() string { return "key_points" }
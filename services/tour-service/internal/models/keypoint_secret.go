@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// KeyPointSecret is hidden content of a key point, revealed only to a tourist whose execution reached it
+type KeyPointSecret struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	KeyPointID uint           `json:"keyPointId" gorm:"uniqueIndex;not null"`
+	Text       string         `json:"text"`
+	Images     pq.StringArray `json:"images" gorm:"type:text[]"` // URLs or paths to images
+	AudioURL   string         `json:"audioUrl"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	UpdatedAt  time.Time      `json:"updatedAt"`
+}
+
+func (KeyPointSecret) TableName() string { return "key_point_secrets" }
+
+// IsEmpty reports whether the secret has no content
+func (s KeyPointSecret) IsEmpty() bool {
+	return s.Text == "" && len(s.Images) == 0 && s.AudioURL == ""
+}
@@ -4,18 +4,44 @@ import (
 	"time"
 )
 
+// Enum for review moderation status
+type ReviewModerationStatus string
+
+const (
+	ReviewVisible  ReviewModerationStatus = "Visible"
+	ReviewHidden   ReviewModerationStatus = "Hidden"   // automatski sakrivena zbog prijava, ceka administratora
+	ReviewApproved ReviewModerationStatus = "Approved" // administrator ju je odobrio, nove prijave je ne sakrivaju
+	ReviewRemoved  ReviewModerationStatus = "Removed"
+)
+
+// VisibleReviewStatuses su statusi recenzija koje se prikazuju i ulaze u prosek ocena ture
+var VisibleReviewStatuses = []ReviewModerationStatus{ReviewVisible, ReviewApproved}
+
+// IsVisible vraca da li se recenzija prikazuje i racuna u prosek
+func (s ReviewModerationStatus) IsVisible() bool {
+	return s == ReviewVisible || s == ReviewApproved
+}
+
 // Review struct represents a review for a tour
 type Review struct {
-	ID              uint      `json:"id" gorm:"primaryKey"`
-	TourID          uint      `json:"tourId" gorm:"not null"`
-	TouristID       uint      `json:"touristId" gorm:"not null"`                // ID korisnika koji je ostavio recenziju
-	TouristUsername string    `json:"touristUsername" gorm:"type:varchar(255)"` // Username turiste
-	Rating          int       `json:"rating" gorm:"not null"`                   // Ocena 1-5
-	Comment         string    `json:"comment" gorm:"type:text"`
-	VisitDate       time.Time `json:"visitDate"`               // Datum kada je posetio turu
-	Images          string    `json:"images" gorm:"type:text"` // JSON array URLs slika
-	CreatedAt       time.Time `json:"createdAt"`               // Datum kada je ostavio komentar
-	UpdatedAt       time.Time `json:"updatedAt"`
+	ID                   uint                   `json:"id" gorm:"primaryKey"`
+	TourID               uint                   `json:"tourId" gorm:"not null;uniqueIndex:idx_review_tour_tourist"`
+	TouristID            uint                   `json:"touristId" gorm:"not null;uniqueIndex:idx_review_tour_tourist"` // ID korisnika koji je ostavio recenziju, jedna recenzija po turi
+	TouristUsername      string                 `json:"touristUsername" gorm:"type:varchar(255)"`                      // Username turiste
+	Rating               int                    `json:"rating" gorm:"not null"`                                        // Ocena 1-5
+	Comment              string                 `json:"comment" gorm:"type:text"`
+	VisitDate            time.Time              `json:"visitDate"`                                 // Datum kada je posetio turu
+	Images               string                 `json:"images" gorm:"type:text"`                   // JSON array URLs slika
+	HelpfulCount         int                    `json:"helpfulCount" gorm:"default:0;not null"`    // Broj glasova da je recenzija korisna
+	AuthorReply          string                 `json:"authorReply,omitempty" gorm:"type:text"`    // Javni odgovor autora ture, najvise jedan po recenziji
+	AuthorReplyAt        *time.Time             `json:"authorReplyAt,omitempty"`                   // Kada je autor prvi put odgovorio
+	AuthorReplyUpdatedAt *time.Time             `json:"authorReplyUpdatedAt,omitempty"`            // Poslednja izmena odgovora
+	ReplyUnread          bool                   `json:"replyUnread" gorm:"default:false;not null"` // Turista jos nije video (novi ili izmenjen) odgovor
+	NotHelpfulCount      int                    `json:"notHelpfulCount" gorm:"default:0;not null"`
+	ReportCount          int                    `json:"reportCount" gorm:"default:0;not null"` // Broj otvorenih prijava
+	ModerationStatus     ReviewModerationStatus `json:"moderationStatus" gorm:"type:varchar(16);default:'Visible';not null;index"`
+	CreatedAt            time.Time              `json:"createdAt"` // Datum kada je ostavio komentar
+	UpdatedAt            time.Time              `json:"updatedAt"`
 
 	// Relacije
 	Tour Tour `json:"tour,omitempty" gorm:"foreignKey:TourID"`
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// ReviewVote je glas korisnika da li je recenzija korisna, jedan po korisniku i recenziji
+type ReviewVote struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ReviewID  uint      `json:"reviewId" gorm:"not null;uniqueIndex:idx_review_vote"`
+	UserID    uint      `json:"userId" gorm:"not null;uniqueIndex:idx_review_vote"`
+	Helpful   bool      `json:"helpful"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (ReviewVote) TableName() string { return "review_votes" }
+
+// Enum for review report reason
+type ReportReason string
+
+const (
+	ReportSpam      ReportReason = "spam"
+	ReportOffensive ReportReason = "offensive"
+	ReportFake      ReportReason = "fake"
+	ReportOffTopic  ReportReason = "off_topic"
+	ReportOther     ReportReason = "other"
+)
+
+// ReviewReport je prijava recenzije, jedna po korisniku i recenziji
+type ReviewReport struct {
+	ID         uint         `json:"id" gorm:"primaryKey"`
+	ReviewID   uint         `json:"reviewId" gorm:"not null;uniqueIndex:idx_review_report"`
+	ReporterID uint         `json:"reporterId" gorm:"not null;uniqueIndex:idx_review_report"`
+	Reason     ReportReason `json:"reason" gorm:"type:varchar(32);not null"`
+	Details    string       `json:"details,omitempty" gorm:"type:text"`
+	ResolvedAt *time.Time   `json:"resolvedAt,omitempty"` // Kada je administrator odlucio o recenziji, nil dok je prijava otvorena
+	Resolution string       `json:"resolution,omitempty" gorm:"type:varchar(32)"`
+	CreatedAt  time.Time    `json:"createdAt"`
+}
+
+func (ReviewReport) TableName() string { return "review_reports" }
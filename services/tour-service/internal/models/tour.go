@@ -26,24 +26,39 @@ const (
 	Expert TourDifficulty = "Expert"
 )
 
+// Enum for key point completion mode
+type CompletionMode string
+
+const (
+	FreeOrder  CompletionMode = "FreeOrder"  // keypointi se obilaze bilo kojim redosledom
+	Sequential CompletionMode = "Sequential" // moze da se zavrsi samo sledeci neposeceni keypoint po Order
+)
+
 // Updated Tour struct
 type Tour struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	AuthorID    uint           `json:"authorId"`
-	Name        string         `json:"name"`
-	Description string         `json:"description"`
-	Difficulty  TourDifficulty `json:"difficulty"`
-	Tags        pq.StringArray `json:"tags" gorm:"type:text[]"`
-	Status      TourStatus     `json:"status" gorm:"default:'Draft'"`
-	Price       float64        `json:"price"`
-	Distance    float64        `json:"distance" gorm:"default:0"` // Distance in kilometers
-	PublishedAt *time.Time     `json:"publishedAt,omitempty"`
-	ArchivedAt  *time.Time     `json:"archivedAt,omitempty"`
-	IsDeleted   bool           `json:"isDeleted" gorm:"default:false"`
-	CreatedAt   time.Time      `json:"createdAt"`
-	UpdatedAt   time.Time      `json:"updatedAt"`
-	KeyPoints   []KeyPoint     `json:"keyPoints,omitempty" gorm:"foreignKey:TourID;constraint:OnDelete:CASCADE"`
-	Durations   []TourDuration `json:"durations,omitempty" gorm:"foreignKey:TourID;constraint:OnDelete:CASCADE"`
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	AuthorID       uint           `json:"authorId"`
+	Name           string         `json:"name"`
+	Description    string         `json:"description"`
+	Difficulty     TourDifficulty `json:"difficulty"`
+	Tags           pq.StringArray `json:"tags" gorm:"type:text[]"`
+	Status         TourStatus     `json:"status" gorm:"default:'Draft'"`
+	Price          float64        `json:"price"`
+	Distance       float64        `json:"distance" gorm:"default:0"`                    // Distance in kilometers
+	RouteEstimated bool           `json:"routeEstimated" gorm:"default:false;not null"` // Distanca i trajanja su procena po vazdusnoj liniji, ne rutirani
+	CompletionMode CompletionMode `json:"completionMode" gorm:"default:'FreeOrder'"`
+	AverageRating  float64        `json:"averageRating" gorm:"default:0;index"` // Prosek ocena iz reviews, menja se zajedno sa recenzijama
+	ReviewCount    int            `json:"reviewCount" gorm:"default:0"`
+	PublishedAt    *time.Time     `json:"publishedAt,omitempty"`
+	ArchivedAt     *time.Time     `json:"archivedAt,omitempty"`
+	IsDeleted      bool           `json:"isDeleted" gorm:"default:false"`
+	DeletedAt      *time.Time     `json:"deletedAt,omitempty"`
+	Version        int            `json:"version" gorm:"default:0"`                                     // Poslednja publishovana verzija, 0 ako tura nikad nije publishovana
+	RevisionOf     *uint          `json:"revisionOf,omitempty" gorm:"uniqueIndex:idx_tour_revision_of"` // Za draft reviziju: ID publishovane ture koju menja, najvise jedna revizija po turi
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+	KeyPoints      []KeyPoint     `json:"keyPoints,omitempty" gorm:"foreignKey:TourID;constraint:OnDelete:CASCADE"`
+	Durations      []TourDuration `json:"durations,omitempty" gorm:"foreignKey:TourID;constraint:OnDelete:CASCADE"`
 }
 
 func (Tour) TableName() string { return "tours" }
@@ -13,14 +13,21 @@ const (
     ExecutionAbandoned TourExecutionStatus = "ABANDONED"
 )
 
+// CanTransitionTo: STARTED moze da postane COMPLETED ili ABANDONED, iz zavrsnih stanja se ne izlazi
+func (s TourExecutionStatus) CanTransitionTo(next TourExecutionStatus) bool {
+    return s == ExecutionStarted && (next == ExecutionCompleted || next == ExecutionAbandoned)
+}
+
 type TourExecution struct {
     ID                 uint                `json:"id" gorm:"primaryKey"`
     TourID             uint                `json:"tourId"`
     TouristID          uint                `json:"touristId"`
+    TourVersion        int                 `json:"tourVersion"` // Verzija ture na kojoj je izvrsavanje zapoceto
     Status             TourExecutionStatus `json:"status"`
     StartTime          time.Time           `json:"startTime"`
     EndTime            *time.Time          `json:"endTime,omitempty"`
-    LastActivity       time.Time           `json:"lastActivity"`
+    AutoAbandoned      bool                `json:"autoAbandoned" gorm:"default:false"` // Napusteno zbog neaktivnosti, ne od strane turiste
+    LastActivity       time.Time           `json:"lastActivity" gorm:"index"`
     CompletedKeyPoints pq.Int64Array       `json:"completedKeyPoints" gorm:"type:integer[]"`
     StartingLatitude   float64             `json:"startingLatitude"`
     StartingLongitude  float64             `json:"startingLongitude"`
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// TourPriceHistory records every change of a tour's price
+type TourPriceHistory struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TourID    uint      `json:"tourId" gorm:"index;not null"`
+	OldPrice  float64   `json:"oldPrice"`
+	NewPrice  float64   `json:"newPrice"`
+	ChangedBy uint      `json:"changedBy"` // ID korisnika koji je promenio cenu
+	ChangedAt time.Time `json:"changedAt" gorm:"index"`
+}
+
+func (TourPriceHistory) TableName() string { return "tour_price_history" }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// TourVersion is an immutable snapshot of a tour taken every time it is published
+type TourVersion struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	TourID      uint      `json:"tourId" gorm:"uniqueIndex:idx_tour_versions_tour_version;not null"`
+	Version     int       `json:"version" gorm:"uniqueIndex:idx_tour_versions_tour_version;not null"`
+	Snapshot    string    `json:"-" gorm:"type:jsonb;not null"` // JSON ture sa keypointima i trajanjima u trenutku publishovanja
+	PublishedAt time.Time `json:"publishedAt"`
+}
+
+func (TourVersion) TableName() string { return "tour_versions" }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// UserBadge je bedz koji je korisnik osvojio, Code odgovara pravilu iz kataloga bedzeva u servisu
+type UserBadge struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"userId" gorm:"not null;uniqueIndex:idx_user_badge"`
+	Code      string    `json:"code" gorm:"type:varchar(64);not null;uniqueIndex:idx_user_badge"`
+	EarnedAt  time.Time `json:"earnedAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (UserBadge) TableName() string { return "user_badges" }
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"tour-service/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BadgeRepository struct {
+	db *gorm.DB
+}
+
+func NewBadgeRepository(db *gorm.DB) *BadgeRepository {
+	return &BadgeRepository{db: db}
+}
+
+// CompletedTour is a tour the user has completed at least once, with the tour data badges are based on
+type CompletedTour struct {
+	TourID      uint
+	AuthorID    uint
+	Difficulty  models.TourDifficulty
+	Distance    float64
+	Status      models.TourStatus
+	IsDeleted   bool
+	Completions int
+}
+
+// FindByUserID returns the badges earned by a user, oldest first
+func (r *BadgeRepository) FindByUserID(userID uint) ([]models.UserBadge, error) {
+	var badges []models.UserBadge
+	err := r.db.Where("user_id = ?", userID).Order("earned_at ASC").Find(&badges).Error
+	return badges, err
+}
+
+// Award stores an earned badge and reports whether it was newly earned
+func (r *BadgeRepository) Award(badge *models.UserBadge) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(badge)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetCompletedTours returns every tour the user has completed, with the number of completions
+func (r *BadgeRepository) GetCompletedTours(userID uint) ([]CompletedTour, error) {
+	var tours []CompletedTour
+	err := r.db.Table("tour_executions AS e").
+		Select("e.tour_id, t.author_id, t.difficulty, t.distance, t.status, t.is_deleted, COUNT(*) AS completions").
+		Joins("JOIN tours t ON t.id = e.tour_id").
+		Where("e.tourist_id = ? AND e.status = ?", userID, models.ExecutionCompleted).
+		Group("e.tour_id, t.author_id, t.difficulty, t.distance, t.status, t.is_deleted").
+		Scan(&tours).Error
+	return tours, err
+}
+
+// CountPublishedToursByAuthor returns the number of published tours for each of the given authors
+func (r *BadgeRepository) CountPublishedToursByAuthor(authorIDs []uint) (map[uint]int, error) {
+	counts := make(map[uint]int)
+	if len(authorIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		AuthorID uint
+		Count    int
+	}
+	err := r.db.Model(&models.Tour{}).
+		Select("author_id, COUNT(*) AS count").
+		Where("author_id IN ? AND status = ? AND is_deleted = ?", authorIDs, models.Published, false).
+		Group("author_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.AuthorID] = row.Count
+	}
+	return counts, nil
+}
@@ -38,12 +38,57 @@ func (r *KeyPointRepository) Update(keyPoint *models.KeyPoint) error {
 	return r.DB.Save(keyPoint).Error
 }
 
-// Delete deletes a key point
+// UpdateLegDistance updates the distance from the previous key point
+func (r *KeyPointRepository) UpdateLegDistance(id uint, distance float64) error {
+	return r.DB.Model(&models.KeyPoint{}).Where("id = ?", id).Update("leg_distance", distance).Error
+}
+
+// Delete deletes a key point together with its secret
 func (r *KeyPointRepository) Delete(id uint) error {
+	if err := r.DB.Where("key_point_id = ?", id).Delete(&models.KeyPointSecret{}).Error; err != nil {
+		return err
+	}
 	return r.DB.Delete(&models.KeyPoint{}, id).Error
 }
 
 // DeleteByTourID deletes all key points for a specific tour
 func (r *KeyPointRepository) DeleteByTourID(tourID uint) error {
-	return r.DB.Where("tour_id = ?", tourID).Delete(&models.KeyPoint{}).Error
+	return deleteKeyPointsOfTour(r.DB, tourID)
+}
+
+// FindSecretsByTourID finds the secrets of all key points of a tour, keyed by key point ID
+func (r *KeyPointRepository) FindSecretsByTourID(tourID uint) (map[uint]*models.KeyPointSecret, error) {
+	var secrets []models.KeyPointSecret
+	err := r.DB.Where("key_point_id IN (?)", r.DB.Model(&models.KeyPoint{}).Select("id").Where("tour_id = ?", tourID)).
+		Find(&secrets).Error
+	if err != nil {
+		return nil, err
+	}
+	byKeyPoint := make(map[uint]*models.KeyPointSecret, len(secrets))
+	for i := range secrets {
+		byKeyPoint[secrets[i].KeyPointID] = &secrets[i]
+	}
+	return byKeyPoint, nil
+}
+
+// SaveSecret creates or replaces the secret of a key point; an empty secret removes it
+func (r *KeyPointRepository) SaveSecret(keyPointID uint, secret models.KeyPointSecret) error {
+	if err := r.DB.Where("key_point_id = ?", keyPointID).Delete(&models.KeyPointSecret{}).Error; err != nil {
+		return err
+	}
+	if secret.IsEmpty() {
+		return nil
+	}
+	secret.ID = 0
+	secret.KeyPointID = keyPointID
+	return r.DB.Create(&secret).Error
+}
+
+// deleteKeyPointsOfTour deletes all key points of a tour together with their secrets
+func deleteKeyPointsOfTour(db *gorm.DB, tourID uint) error {
+	keyPointIDs := db.Model(&models.KeyPoint{}).Select("id").Where("tour_id = ?", tourID)
+	if err := db.Where("key_point_id IN (?)", keyPointIDs).Delete(&models.KeyPointSecret{}).Error; err != nil {
+		return err
+	}
+	return db.Where("tour_id = ?", tourID).Delete(&models.KeyPoint{}).Error
 }
\ No newline at end of file
@@ -1,9 +1,13 @@
 package repository
 
 import (
+	"errors"
+	"time"
+	"tour-service/internal/dto"
 	"tour-service/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ReviewRepository struct {
@@ -14,6 +18,11 @@ func NewReviewRepository(db *gorm.DB) *ReviewRepository {
 	return &ReviewRepository{db: db}
 }
 
+// visibleReviews limits a query to reviews that are shown and count towards the tour rating
+func visibleReviews(db *gorm.DB) *gorm.DB {
+	return db.Where("moderation_status IN ?", models.VisibleReviewStatuses)
+}
+
 // Create creates a new review
 func (r *ReviewRepository) Create(review *models.Review) error {
 	return r.db.Create(review).Error
@@ -32,10 +41,33 @@ func (r *ReviewRepository) FindByID(id uint) (*models.Review, error) {
 // FindByTourID finds all reviews for a specific tour
 func (r *ReviewRepository) FindByTourID(tourID uint) ([]models.Review, error) {
 	var reviews []models.Review
-	err := r.db.Where("tour_id = ?", tourID).Order("created_at DESC").Find(&reviews).Error
+	err := r.db.Scopes(visibleReviews).Where("tour_id = ?", tourID).Order("created_at DESC").Find(&reviews).Error
 	return reviews, err
 }
 
+// FindByIDForUpdate finds a review by ID and locks its row until the end of the transaction
+func (r *ReviewRepository) FindByIDForUpdate(id uint) (*models.Review, error) {
+	var review models.Review
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&review, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// FindByTourAndTourist finds the review a tourist left for a tour, or nil if there is none
+func (r *ReviewRepository) FindByTourAndTourist(tourID, touristID uint) (*models.Review, error) {
+	var review models.Review
+	err := r.db.Where("tour_id = ? AND tourist_id = ?", tourID, touristID).First(&review).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
 // FindByTouristID finds all reviews by a specific tourist
 func (r *ReviewRepository) FindByTouristID(touristID uint) ([]models.Review, error) {
 	var reviews []models.Review
@@ -48,15 +80,35 @@ func (r *ReviewRepository) Update(review *models.Review) error {
 	return r.db.Save(review).Error
 }
 
+// UpdateFields updates only the given columns of a review
+func (r *ReviewRepository) UpdateFields(id uint, fields map[string]interface{}) error {
+	return r.db.Model(&models.Review{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// CountUnreadReplies returns how many of a tourist's reviews have an author reply the tourist has not seen
+func (r *ReviewRepository) CountUnreadReplies(touristID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Review{}).Where("tourist_id = ? AND reply_unread = ?", touristID, true).Count(&count).Error
+	return count, err
+}
+
 // Delete deletes a review
 func (r *ReviewRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Review{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// glasovi i prijave nemaju smisla bez recenzije
+		for _, model := range []interface{}{&models.ReviewVote{}, &models.ReviewReport{}} {
+			if err := tx.Where("review_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&models.Review{}, id).Error
+	})
 }
 
 // GetAverageRating calculates the average rating for a tour
 func (r *ReviewRepository) GetAverageRating(tourID uint) (float64, error) {
 	var avgRating float64
-	err := r.db.Model(&models.Review{}).
+	err := r.db.Model(&models.Review{}).Scopes(visibleReviews).
 		Where("tour_id = ?", tourID).
 		Select("COALESCE(AVG(rating), 0)").
 		Scan(&avgRating).Error
@@ -66,6 +118,173 @@ func (r *ReviewRepository) GetAverageRating(tourID uint) (float64, error) {
 // GetReviewCount returns the count of reviews for a tour
 func (r *ReviewRepository) GetReviewCount(tourID uint) (int64, error) {
 	var count int64
-	err := r.db.Model(&models.Review{}).Where("tour_id = ?", tourID).Count(&count).Error
+	err := r.db.Model(&models.Review{}).Scopes(visibleReviews).Where("tour_id = ?", tourID).Count(&count).Error
 	return count, err
 }
+
+var reviewSortOrders = map[string]string{
+	"newest":       "created_at DESC, id DESC",
+	"highest":      "rating DESC, created_at DESC, id DESC",
+	"lowest":       "rating ASC, created_at DESC, id DESC",
+	"most_helpful": "helpful_count DESC, created_at DESC, id DESC",
+}
+
+// FindPageByTourID finds one page of a tour's reviews matching the query and the total match count
+func (r *ReviewRepository) FindPageByTourID(tourID uint, query dto.ReviewListQuery) ([]models.Review, int64, error) {
+	db := r.db.Model(&models.Review{}).Scopes(visibleReviews).Where("tour_id = ?", tourID)
+	if len(query.Ratings) > 0 {
+		db = db.Where("rating IN ?", query.Ratings)
+	}
+	if query.WithPhotos {
+		db = db.Where("images IS NOT NULL AND images NOT IN ('', 'null', '[]')")
+	}
+
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order, ok := reviewSortOrders[query.Sort]
+	if !ok {
+		order = reviewSortOrders["newest"]
+	}
+
+	var reviews []models.Review
+	err := db.Session(&gorm.Session{}).
+		Order(order).
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&reviews).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return reviews, total, nil
+}
+
+// GetRatingHistogram returns the number of reviews of a tour for each rating
+func (r *ReviewRepository) GetRatingHistogram(tourID uint) (map[int]int64, error) {
+	var rows []struct {
+		Rating int
+		Count  int64
+	}
+	err := r.db.Model(&models.Review{}).Scopes(visibleReviews).
+		Select("rating, COUNT(*) AS count").
+		Where("tour_id = ?", tourID).
+		Group("rating").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	histogram := make(map[int]int64)
+	for _, row := range rows {
+		histogram[row.Rating] = row.Count
+	}
+	return histogram, nil
+}
+
+// GetRatingSummary returns the review count and average rating of a tour for reviews created in [from, to).
+// A nil bound is open.
+func (r *ReviewRepository) GetRatingSummary(tourID uint, from, to *time.Time) (int64, float64, error) {
+	db := r.db.Model(&models.Review{}).Scopes(visibleReviews).Where("tour_id = ?", tourID)
+	if from != nil {
+		db = db.Where("created_at >= ?", *from)
+	}
+	if to != nil {
+		db = db.Where("created_at < ?", *to)
+	}
+
+	var summary struct {
+		Count   int64
+		Average float64
+	}
+	err := db.Select("COUNT(*) AS count, COALESCE(AVG(rating), 0) AS average").Scan(&summary).Error
+	return summary.Count, summary.Average, err
+}
+
+// UpsertVote stores a user's vote on a review, replacing their previous vote
+func (r *ReviewRepository) UpsertVote(vote *models.ReviewVote) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "review_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"helpful", "updated_at"}),
+	}).Create(vote).Error
+}
+
+// DeleteVote removes a user's vote on a review and reports whether there was one
+func (r *ReviewRepository) DeleteVote(reviewID, userID uint) (bool, error) {
+	result := r.db.Where("review_id = ? AND user_id = ?", reviewID, userID).Delete(&models.ReviewVote{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// RecountVotes recomputes the helpful and not helpful counts of a review from its votes
+func (r *ReviewRepository) RecountVotes(reviewID uint) error {
+	return r.db.Exec(`UPDATE reviews SET
+		helpful_count = (SELECT COUNT(*) FROM review_votes v WHERE v.review_id = reviews.id AND v.helpful),
+		not_helpful_count = (SELECT COUNT(*) FROM review_votes v WHERE v.review_id = reviews.id AND NOT v.helpful)
+		WHERE id = ?`, reviewID).Error
+}
+
+// FindReport finds a user's report of a review, or nil if there is none
+func (r *ReviewRepository) FindReport(reviewID, reporterID uint) (*models.ReviewReport, error) {
+	var report models.ReviewReport
+	err := r.db.Where("review_id = ? AND reporter_id = ?", reviewID, reporterID).First(&report).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// CreateReport stores a report of a review
+func (r *ReviewRepository) CreateReport(report *models.ReviewReport) error {
+	return r.db.Create(report).Error
+}
+
+// CountOpenReports returns the number of unresolved reports of a review
+func (r *ReviewRepository) CountOpenReports(reviewID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.ReviewReport{}).Where("review_id = ? AND resolved_at IS NULL", reviewID).Count(&count).Error
+	return count, err
+}
+
+// ResolveReports closes every open report of a review with the moderator's resolution
+func (r *ReviewRepository) ResolveReports(reviewID uint, resolution string, at time.Time) error {
+	return r.db.Model(&models.ReviewReport{}).
+		Where("review_id = ? AND resolved_at IS NULL", reviewID).
+		Updates(map[string]interface{}{"resolved_at": at, "resolution": resolution}).Error
+}
+
+// FindModerationQueue finds one page of reviews waiting for a moderator: hidden reviews and reviews
+// with open reports, hidden and most reported first
+func (r *ReviewRepository) FindModerationQueue(page, limit int) ([]models.Review, int64, error) {
+	db := r.db.Model(&models.Review{}).
+		Where("moderation_status = ? OR (report_count > 0 AND moderation_status <> ?)", models.ReviewHidden, models.ReviewRemoved)
+
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var reviews []models.Review
+	err := db.Session(&gorm.Session{}).
+		Order("moderation_status = 'Hidden' DESC, report_count DESC, id ASC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&reviews).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return reviews, total, nil
+}
+
+// FindOpenReportsByReviewIDs returns the unresolved reports of the given reviews, oldest first
+func (r *ReviewRepository) FindOpenReportsByReviewIDs(reviewIDs []uint) ([]models.ReviewReport, error) {
+	var reports []models.ReviewReport
+	if len(reviewIDs) == 0 {
+		return reports, nil
+	}
+	err := r.db.Where("review_id IN ? AND resolved_at IS NULL", reviewIDs).Order("created_at ASC").Find(&reports).Error
+	return reports, err
+}
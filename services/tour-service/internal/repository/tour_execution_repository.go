@@ -1,10 +1,14 @@
 package repository
 
 import (
+	"encoding/json"
 	"tour-service/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"errors"
+	"sort"
 	"strconv"
+	"time"
 )
 
 type TourExecutionRepository struct {
@@ -26,6 +30,41 @@ func (r *TourExecutionRepository) GetByID(executionID uint) (*models.TourExecuti
 	return &execution, err
 }
 
+// GetByIDForUpdate finds an execution and locks its row until the end of the transaction
+func (r *TourExecutionRepository) GetByIDForUpdate(executionID uint) (*models.TourExecution, error) {
+	var execution models.TourExecution
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&execution, executionID).Error
+	return &execution, err
+}
+
+// AbandonInactive marks up to limit STARTED executions with no activity since cutoff as ABANDONED
+// and returns them. Rows locked by another transaction (a running request or another replica) are skipped.
+func (r *TourExecutionRepository) AbandonInactive(cutoff time.Time, now time.Time, limit int) ([]models.TourExecution, error) {
+	var stale []models.TourExecution
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND last_activity < ?", models.ExecutionStarted, cutoff).
+			Order("id").Limit(limit).Find(&stale).Error
+		if err != nil || len(stale) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(stale))
+		for i := range stale {
+			ids[i] = stale[i].ID
+			stale[i].Status = models.ExecutionAbandoned
+			stale[i].EndTime = &now
+			stale[i].AutoAbandoned = true
+		}
+		return tx.Model(&models.TourExecution{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":         models.ExecutionAbandoned,
+			"end_time":       now,
+			"auto_abandoned": true,
+		}).Error
+	})
+	return stale, err
+}
+
 func (r *TourExecutionRepository) Update(execution *models.TourExecution) (*models.TourExecution, error) {
 	err := r.DB.Save(execution).Error
 	return execution, err
@@ -56,6 +95,109 @@ func (r *TourExecutionRepository) GetKeyPointsByTour(tourID uint) ([]models.KeyP
 	return keyPoints, err
 }
 
+// GetTourVersion vraca trenutno publishovanu verziju ture
+func (r *TourExecutionRepository) GetTourVersion(tourID uint) (int, error) {
+	var tour models.Tour
+	err := r.DB.Select("id", "version").First(&tour, tourID).Error
+	return tour.Version, err
+}
+
+// GetTourWithKeyPoints vraca trenutno stanje ture sa keypointima po redosledu
+func (r *TourExecutionRepository) GetTourWithKeyPoints(tourID uint) (*models.Tour, error) {
+	var tour models.Tour
+	err := r.DB.Preload("KeyPoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("\"order\" ASC")
+	}).First(&tour, tourID).Error
+	return &tour, err
+}
+
+// GetTourByVersion vraca turu sa keypointima onakvu kakva je bila u publishovanoj verziji
+func (r *TourExecutionRepository) GetTourByVersion(tourID uint, version int) (*models.Tour, error) {
+	var tourVersion models.TourVersion
+	if err := r.DB.Where("tour_id = ? AND version = ?", tourID, version).First(&tourVersion).Error; err != nil {
+		return nil, err
+	}
+
+	var snapshot models.Tour
+	if err := json.Unmarshal([]byte(tourVersion.Snapshot), &snapshot); err != nil {
+		return nil, err
+	}
+	sort.Slice(snapshot.KeyPoints, func(i, j int) bool {
+		return snapshot.KeyPoints[i].Order < snapshot.KeyPoints[j].Order
+	})
+	return &snapshot, nil
+}
+
+// CreateTrackPoint stores one reported position of an execution
+func (r *TourExecutionRepository) CreateTrackPoint(point *models.ExecutionTrackPoint) error {
+	return r.DB.Create(point).Error
+}
+
+// CreateTrackPoints stores a batch of positions of an execution
+func (r *TourExecutionRepository) CreateTrackPoints(points []models.ExecutionTrackPoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+	return r.DB.CreateInBatches(points, 500).Error
+}
+
+// GetTrackPointTimes returns the recording times of an execution's positions between from and to (inclusive)
+func (r *TourExecutionRepository) GetTrackPointTimes(executionID uint, from, to time.Time) ([]time.Time, error) {
+	var times []time.Time
+	err := r.DB.Model(&models.ExecutionTrackPoint{}).
+		Where("execution_id = ? AND recorded_at BETWEEN ? AND ?", executionID, from, to).
+		Pluck("recorded_at", &times).Error
+	return times, err
+}
+
+// GetTrackPoints returns all positions of an execution in the order they were recorded
+func (r *TourExecutionRepository) GetTrackPoints(executionID uint) ([]models.ExecutionTrackPoint, error) {
+	var points []models.ExecutionTrackPoint
+	err := r.DB.Where("execution_id = ?", executionID).Order("recorded_at ASC, id ASC").Find(&points).Error
+	return points, err
+}
+
+// GetLastTrackPoint returns the most recent position of an execution, or nil if none was reported
+func (r *TourExecutionRepository) GetLastTrackPoint(executionID uint) (*models.ExecutionTrackPoint, error) {
+	var point models.ExecutionTrackPoint
+	err := r.DB.Where("execution_id = ?", executionID).Order("recorded_at DESC, id DESC").First(&point).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &point, nil
+}
+
+// GetTourAuthorID vraca autora ture
+func (r *TourExecutionRepository) GetTourAuthorID(tourID uint) (uint, error) {
+	var tour models.Tour
+	err := r.DB.Select("id", "author_id").First(&tour, tourID).Error
+	return tour.AuthorID, err
+}
+
+// GetExecutionsByTouristAndTour returns every execution of a tour by one tourist
+func (r *TourExecutionRepository) GetExecutionsByTouristAndTour(touristID uint, tourID uint) ([]models.TourExecution, error) {
+	var executions []models.TourExecution
+	err := r.DB.Where("tourist_id = ? AND tour_id = ?", touristID, tourID).Find(&executions).Error
+	return executions, err
+}
+
+// GetExecutionsByTourStartedBetween returns executions of a tour started in [from, to); nil bounds are open
+func (r *TourExecutionRepository) GetExecutionsByTourStartedBetween(tourID uint, from, to *time.Time) ([]models.TourExecution, error) {
+	query := r.DB.Where("tour_id = ?", tourID)
+	if from != nil {
+		query = query.Where("start_time >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("start_time < ?", *to)
+	}
+	var executions []models.TourExecution
+	err := query.Find(&executions).Error
+	return executions, err
+}
+
 func (r *TourExecutionRepository) GetExecutionsByTour(tourID uint) ([]models.TourExecution, error) {
     var executions []models.TourExecution
     err := r.DB.Where("tour_id = ?", tourID).Find(&executions).Error
@@ -1,10 +1,15 @@
 package repository
 
 import (
+	"errors"
+	"strings"
 	"time"
+	"tour-service/internal/dto"
 	"tour-service/internal/models"
 
+	"github.com/lib/pq"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // TourRepository brine o komunikaciji sa bazom
@@ -23,14 +28,23 @@ func (r *TourRepository) Create(tour *models.Tour) error {
 
 func (r *TourRepository) FindByAuthorID(authorID uint) ([]models.Tour, error) {
 	var tours []models.Tour
-	if err := r.DB.Where("author_id = ?", authorID).Find(&tours).Error; err != nil {
+	if err := r.DB.Where("author_id = ? AND is_deleted = ?", authorID, false).Find(&tours).Error; err != nil {
 		return nil, err
 	}
 	return tours, nil
 }
 
-// FindByID finds tour by ID
+// FindByID finds tour by ID, soft-deleted tours are not found
 func (r *TourRepository) FindByID(tourID uint) (*models.Tour, error) {
+	var tour models.Tour
+	if err := r.DB.Where("is_deleted = ?", false).First(&tour, tourID).Error; err != nil {
+		return nil, err
+	}
+	return &tour, nil
+}
+
+// FindByIDIncludingDeleted finds tour by ID even if it is soft-deleted
+func (r *TourRepository) FindByIDIncludingDeleted(tourID uint) (*models.Tour, error) {
 	var tour models.Tour
 	if err := r.DB.First(&tour, tourID).Error; err != nil {
 		return nil, err
@@ -38,10 +52,32 @@ func (r *TourRepository) FindByID(tourID uint) (*models.Tour, error) {
 	return &tour, nil
 }
 
+// FindByIDForUpdate finds tour by ID and locks its row until the end of the transaction
+func (r *TourRepository) FindByIDForUpdate(tourID uint) (*models.Tour, error) {
+	var tour models.Tour
+	if err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tour, tourID).Error; err != nil {
+		return nil, err
+	}
+	return &tour, nil
+}
+
 // FindByIDWithRelations finds tour with keypoints and durations
 func (r *TourRepository) FindByIDWithRelations(tourID uint) (*models.Tour, error) {
 	var tour models.Tour
-	if err := r.DB.Preload("KeyPoints").Preload("Durations").First(&tour, tourID).Error; err != nil {
+	if err := r.DB.Preload("KeyPoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("\"order\" ASC")
+	}).Preload("Durations").First(&tour, tourID).Error; err != nil {
+		return nil, err
+	}
+	return &tour, nil
+}
+
+// FindByIDWithSecrets finds a tour with its key points including their secrets, used for version snapshots
+func (r *TourRepository) FindByIDWithSecrets(tourID uint) (*models.Tour, error) {
+	var tour models.Tour
+	if err := r.DB.Preload("KeyPoints", func(db *gorm.DB) *gorm.DB {
+		return db.Order("\"order\" ASC")
+	}).Preload("KeyPoints.Secret").Preload("Durations").First(&tour, tourID).Error; err != nil {
 		return nil, err
 	}
 	return &tour, nil
@@ -52,12 +88,55 @@ func (r *TourRepository) CreateDuration(duration *models.TourDuration) error {
 	return r.DB.Create(duration).Error
 }
 
-// PublishTour updates tour status to published
-func (r *TourRepository) PublishTour(tourID uint) error {
+// ReplaceSuggestedDurations replaces automatically estimated durations of a tour.
+// Transport types the author entered manually keep the author's duration.
+func (r *TourRepository) ReplaceSuggestedDurations(tourID uint, durations []models.TourDuration) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tour_id = ? AND suggested = ?", tourID, true).Delete(&models.TourDuration{}).Error; err != nil {
+			return err
+		}
+
+		var manual []models.TransportType
+		if err := tx.Model(&models.TourDuration{}).
+			Where("tour_id = ? AND suggested = ?", tourID, false).
+			Pluck("transport_type", &manual).Error; err != nil {
+			return err
+		}
+
+		for i := range durations {
+			if containsTransportType(manual, durations[i].TransportType) {
+				continue
+			}
+			if err := tx.Create(&durations[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteSuggestedDuration removes the estimated duration for one transport type
+func (r *TourRepository) DeleteSuggestedDuration(tourID uint, transport models.TransportType) error {
+	return r.DB.Where("tour_id = ? AND transport_type = ? AND suggested = ?", tourID, transport, true).
+		Delete(&models.TourDuration{}).Error
+}
+
+func containsTransportType(types []models.TransportType, transport models.TransportType) bool {
+	for _, t := range types {
+		if t == transport {
+			return true
+		}
+	}
+	return false
+}
+
+// PublishTour updates tour status to published and sets its published version
+func (r *TourRepository) PublishTour(tourID uint, version int) error {
 	now := time.Now()
 	return r.DB.Model(&models.Tour{}).Where("id = ?", tourID).Updates(map[string]interface{}{
 		"status":       models.Published,
 		"published_at": now,
+		"version":      version,
 	}).Error
 }
 
@@ -78,17 +157,351 @@ func (r *TourRepository) ActivateTour(tourID uint) error {
 	}).Error
 }
 
+// UpdatePrice changes the tour price and records the change in price history in one transaction
+func (r *TourRepository) UpdatePrice(tourID uint, newPrice float64, changedBy uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var tour models.Tour
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tour, tourID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Tour{}).Where("id = ?", tourID).Update("price", newPrice).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.TourPriceHistory{
+			TourID:    tourID,
+			OldPrice:  tour.Price,
+			NewPrice:  newPrice,
+			ChangedBy: changedBy,
+			ChangedAt: time.Now(),
+		}).Error
+	})
+}
+
+// CreatePriceHistory stores a single price history entry
+func (r *TourRepository) CreatePriceHistory(entry *models.TourPriceHistory) error {
+	return r.DB.Create(entry).Error
+}
+
+// FindPriceHistory returns all price changes of a tour, newest first
+func (r *TourRepository) FindPriceHistory(tourID uint) ([]models.TourPriceHistory, error) {
+	var history []models.TourPriceHistory
+	err := r.DB.Where("tour_id = ?", tourID).Order("changed_at DESC, id DESC").Find(&history).Error
+	return history, err
+}
+
+// FindPriceAt returns the price change that was in effect at the given moment
+func (r *TourRepository) FindPriceAt(tourID uint, at time.Time) (*models.TourPriceHistory, error) {
+	var entry models.TourPriceHistory
+	err := r.DB.Where("tour_id = ? AND changed_at <= ?", tourID, at).
+		Order("changed_at DESC, id DESC").
+		First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// CreateVersion stores an immutable published version of a tour
+func (r *TourRepository) CreateVersion(version *models.TourVersion) error {
+	return r.DB.Create(version).Error
+}
+
+// FindVersions lists published versions of a tour without their snapshots, newest first
+func (r *TourRepository) FindVersions(tourID uint) ([]models.TourVersion, error) {
+	var versions []models.TourVersion
+	err := r.DB.Select("id", "tour_id", "version", "published_at").
+		Where("tour_id = ?", tourID).
+		Order("version DESC").
+		Find(&versions).Error
+	return versions, err
+}
+
+// FindVersion finds one published version of a tour
+func (r *TourRepository) FindVersion(tourID uint, version int) (*models.TourVersion, error) {
+	var tourVersion models.TourVersion
+	if err := r.DB.Where("tour_id = ? AND version = ?", tourID, version).First(&tourVersion).Error; err != nil {
+		return nil, err
+	}
+	return &tourVersion, nil
+}
+
+// FindRevision finds the open draft revision of a published tour, nil if there is none
+func (r *TourRepository) FindRevision(tourID uint) (*models.Tour, error) {
+	var tour models.Tour
+	err := r.DB.Where("revision_of = ?", tourID).First(&tour).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tour, nil
+}
+
+// CopyRoute copies key points (with their secrets) and durations of one tour to another
+func (r *TourRepository) CopyRoute(fromTourID, toTourID uint) error {
+	var keyPoints []models.KeyPoint
+	if err := r.DB.Preload("Secret").Where("tour_id = ?", fromTourID).Order("\"order\" ASC").Find(&keyPoints).Error; err != nil {
+		return err
+	}
+	for _, kp := range keyPoints {
+		kp.ID = 0
+		kp.TourID = toTourID
+		if kp.Secret != nil {
+			kp.Secret.ID = 0
+			kp.Secret.KeyPointID = 0
+		}
+		if err := r.DB.Create(&kp).Error; err != nil {
+			return err
+		}
+	}
+
+	var durations []models.TourDuration
+	if err := r.DB.Where("tour_id = ?", fromTourID).Find(&durations).Error; err != nil {
+		return err
+	}
+	for _, d := range durations {
+		d.ID = 0
+		d.TourID = toTourID
+		if err := r.DB.Create(&d).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MoveRoute replaces key points and durations of toTourID with those of fromTourID
+func (r *TourRepository) MoveRoute(fromTourID, toTourID uint) error {
+	if err := deleteKeyPointsOfTour(r.DB, toTourID); err != nil {
+		return err
+	}
+	if err := r.DB.Where("tour_id = ?", toTourID).Delete(&models.TourDuration{}).Error; err != nil {
+		return err
+	}
+	if err := r.DB.Model(&models.KeyPoint{}).Where("tour_id = ?", fromTourID).Update("tour_id", toTourID).Error; err != nil {
+		return err
+	}
+	return r.DB.Model(&models.TourDuration{}).Where("tour_id = ?", fromTourID).Update("tour_id", toTourID).Error
+}
+
+// UpdateFields updates the given columns of a tour
+func (r *TourRepository) UpdateFields(tourID uint, fields map[string]interface{}) error {
+	return r.DB.Model(&models.Tour{}).Where("id = ?", tourID).Updates(fields).Error
+}
+
+// Delete permanently deletes a tour with its key points (and their secrets), durations and price history
+func (r *TourRepository) Delete(tourID uint) error {
+	if err := deleteKeyPointsOfTour(r.DB, tourID); err != nil {
+		return err
+	}
+	if err := r.DB.Where("tour_id = ?", tourID).Delete(&models.TourDuration{}).Error; err != nil {
+		return err
+	}
+	if err := r.DB.Where("tour_id = ?", tourID).Delete(&models.TourPriceHistory{}).Error; err != nil {
+		return err
+	}
+	return r.DB.Delete(&models.Tour{}, tourID).Error
+}
+
+// SoftDelete marks a tour as deleted, buyers keep access to it
+func (r *TourRepository) SoftDelete(tourID uint) error {
+	return r.DB.Model(&models.Tour{}).Where("id = ?", tourID).Updates(map[string]interface{}{
+		"is_deleted": true,
+		"deleted_at": time.Now(),
+	}).Error
+}
+
+// Restore clears the soft-delete flag of a tour
+func (r *TourRepository) Restore(tourID uint) error {
+	return r.DB.Model(&models.Tour{}).Where("id = ?", tourID).Updates(map[string]interface{}{
+		"is_deleted": false,
+		"deleted_at": nil,
+	}).Error
+}
+
+// Purge permanently deletes a tour and everything that references it in one transaction
+func (r *TourRepository) Purge(tourID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		executions := tx.Model(&models.TourExecution{}).Select("id").Where("tour_id = ?", tourID)
+		if err := tx.Where("execution_id IN (?)", executions).Delete(&models.ExecutionTrackPoint{}).Error; err != nil {
+			return err
+		}
+		reviews := tx.Model(&models.Review{}).Select("id").Where("tour_id = ?", tourID)
+		for _, model := range []interface{}{&models.ReviewVote{}, &models.ReviewReport{}} {
+			if err := tx.Where("review_id IN (?)", reviews).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		related := []interface{}{&models.Review{}, &models.TourExecution{}, &models.TourVersion{}}
+		for _, model := range related {
+			if err := tx.Where("tour_id = ?", tourID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return NewTourRepository(tx).Delete(tourID)
+	})
+}
+
 // UpdateDistance updates tour distance
 func (r *TourRepository) UpdateDistance(tourID uint, distance float64) error {
 	return r.DB.Model(&models.Tour{}).Where("id = ?", tourID).Update("distance", distance).Error
 }
 
+// UpdateRoute stores the tour distance and whether it is a straight-line estimate rather than a routed one
+func (r *TourRepository) UpdateRoute(tourID uint, distance float64, estimated bool) error {
+	return r.DB.Model(&models.Tour{}).Where("id = ?", tourID).Updates(map[string]interface{}{
+		"distance":        distance,
+		"route_estimated": estimated,
+	}).Error
+}
+
+// visibleReviewSQL: u prosek ulaze samo recenzije koje nisu sakrivene ili uklonjene moderacijom
+const visibleReviewSQL = "r.moderation_status IN ('Visible', 'Approved')"
+
+// ratingAggregateSQL racuna prosek i broj recenzija ture iz tabele reviews
+const ratingAggregateSQL = `UPDATE tours SET
+	average_rating = COALESCE((SELECT AVG(r.rating) FROM reviews r WHERE r.tour_id = tours.id AND ` + visibleReviewSQL + `), 0),
+	review_count = (SELECT COUNT(*) FROM reviews r WHERE r.tour_id = tours.id AND ` + visibleReviewSQL + `)`
+
+// RecalculateRating recomputes AverageRating and ReviewCount of a tour from its reviews
+func (r *TourRepository) RecalculateRating(tourID uint) error {
+	return r.DB.Exec(ratingAggregateSQL+" WHERE id = ?", tourID).Error
+}
+
+// RecalculateAllRatings recomputes AverageRating and ReviewCount of every tour and returns the number of corrected tours
+func (r *TourRepository) RecalculateAllRatings() (int64, error) {
+	result := r.DB.Exec(`WITH computed AS (
+		SELECT t.id, COALESCE(AVG(r.rating), 0) AS average_rating, COUNT(r.id) AS review_count
+		FROM tours t LEFT JOIN reviews r ON r.tour_id = t.id AND ` + visibleReviewSQL + `
+		GROUP BY t.id
+	)
+	UPDATE tours SET average_rating = computed.average_rating, review_count = computed.review_count
+	FROM computed
+	WHERE tours.id = computed.id
+	AND (tours.average_rating IS DISTINCT FROM computed.average_rating OR tours.review_count IS DISTINCT FROM computed.review_count)`)
+	return result.RowsAffected, result.Error
+}
+
 // FindAllPublished finds all published tours with their first keypoint only
 func (r *TourRepository) FindAllPublished() ([]models.Tour, error) {
 	var tours []models.Tour
 	// Get all published tours with only the first keypoint (order = 1)
 	if err := r.DB.Preload("KeyPoints", "\"order\" = 1").
-		Where("status = ?", models.Published).
+		Where("status = ? AND is_deleted = ?", models.Published, false).
+		Find(&tours).Error; err != nil {
+		return nil, err
+	}
+	return tours, nil
+}
+
+// tourSortOrders maps the public sort keys to ORDER BY clauses
+var tourSortOrders = map[string]string{
+	"newest":        "published_at DESC NULLS LAST, id DESC",
+	"price_asc":     "price ASC, id ASC",
+	"price_desc":    "price DESC, id DESC",
+	"distance_asc":  "distance ASC, id ASC",
+	"distance_desc": "distance DESC, id DESC",
+	"rating":        "average_rating DESC, review_count DESC, id DESC",
+}
+
+// SearchPublished finds one page of published tours matching the query and the total match count
+func (r *TourRepository) SearchPublished(query dto.TourSearchQuery) ([]models.Tour, int64, error) {
+	db := r.DB.Model(&models.Tour{}).Where("status = ? AND is_deleted = ?", models.Published, false)
+
+	if len(query.Difficulties) > 0 {
+		db = db.Where("difficulty IN ?", query.Difficulties)
+	}
+	if len(query.Tags) > 0 {
+		if query.MatchAllTags {
+			db = db.Where("tags @> ?", pq.StringArray(query.Tags))
+		} else {
+			db = db.Where("tags && ?", pq.StringArray(query.Tags))
+		}
+	}
+	if query.MinPrice != nil {
+		db = db.Where("price >= ?", *query.MinPrice)
+	}
+	if query.MaxPrice != nil {
+		db = db.Where("price <= ?", *query.MaxPrice)
+	}
+	if query.MinDistance != nil {
+		db = db.Where("distance >= ?", *query.MinDistance)
+	}
+	if query.MaxDistance != nil {
+		db = db.Where("distance <= ?", *query.MaxDistance)
+	}
+	if query.MinRating != nil {
+		db = db.Where("average_rating >= ?", *query.MinRating)
+	}
+	if query.TransportType != "" {
+		db = db.Where("EXISTS (SELECT 1 FROM tour_durations d WHERE d.tour_id = tours.id AND d.transport_type = ?)", query.TransportType)
+	}
+	if query.Text != "" {
+		pattern := containsPattern(query.Text)
+		db = db.Where("(name ILIKE ? OR description ILIKE ?)", pattern, pattern)
+	}
+
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order, ok := tourSortOrders[query.Sort]
+	if !ok {
+		order = tourSortOrders["newest"]
+	}
+
+	var tours []models.Tour
+	err := db.Session(&gorm.Session{}).
+		Preload("KeyPoints", "\"order\" = 1").
+		Order(order).
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&tours).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return tours, total, nil
+}
+
+// likeEscaper escapes LIKE wildcards; backslash is the default LIKE escape character in PostgreSQL
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern builds a LIKE pattern matching text literally anywhere in the value
+func containsPattern(text string) string {
+	return "%" + likeEscaper.Replace(text) + "%"
+}
+
+// FindPublishedKeyPointsInBox finds key points of published tours inside a lat/lng bounding box.
+// The box may wrap around the antimeridian, in which case minLng is greater than maxLng.
+func (r *TourRepository) FindPublishedKeyPointsInBox(minLat, maxLat, minLng, maxLng float64, startOnly bool) ([]models.KeyPoint, error) {
+	db := r.DB.Model(&models.KeyPoint{}).
+		Joins("JOIN tours ON tours.id = key_points.tour_id").
+		Where("tours.status = ? AND tours.is_deleted = ?", models.Published, false).
+		Where("key_points.latitude BETWEEN ? AND ?", minLat, maxLat)
+
+	if minLng <= maxLng {
+		db = db.Where("key_points.longitude BETWEEN ? AND ?", minLng, maxLng)
+	} else {
+		db = db.Where("(key_points.longitude >= ? OR key_points.longitude <= ?)", minLng, maxLng)
+	}
+	if startOnly {
+		db = db.Where("key_points.\"order\" = 1")
+	}
+
+	var keyPoints []models.KeyPoint
+	err := db.Select("key_points.*").Find(&keyPoints).Error
+	return keyPoints, err
+}
+
+// FindPublishedByIDs finds published tours by IDs with their first keypoint only
+func (r *TourRepository) FindPublishedByIDs(ids []uint) ([]models.Tour, error) {
+	var tours []models.Tour
+	if len(ids) == 0 {
+		return tours, nil
+	}
+	if err := r.DB.Preload("KeyPoints", "\"order\" = 1").
+		Where("id IN ? AND status = ? AND is_deleted = ?", ids, models.Published, false).
 		Find(&tours).Error; err != nil {
 		return nil, err
 	}
@@ -0,0 +1,17 @@
+package repository
+
+import "testing"
+
+func TestContainsPatternEscapesWildcards(t *testing.T) {
+	cases := map[string]string{
+		"lake":       "%lake%",
+		"100%":       `%100\%%`,
+		"snake_case": `%snake\_case%`,
+		`C:\tours`:   `%C:\\tours%`,
+	}
+	for text, want := range cases {
+		if got := containsPattern(text); got != want {
+			t.Errorf("containsPattern(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"log"
+	"math"
+	"time"
+	"tour-service/internal/dto"
+	"tour-service/internal/models"
+	"tour-service/internal/repository"
+)
+
+// minAuthorTours: bedz za sve ture jednog autora se ne dobija za autora sa samo jednom-dve ture
+const minAuthorTours = 3
+
+// achievementStats su podaci iz istorije izvrsavanja korisnika na osnovu kojih se racunaju bedzevi
+type achievementStats struct {
+	completedTours    int                           // razlicite zavrsene ture
+	totalKm           float64                       // zbir duzina svih zavrsenih izvrsavanja
+	difficulties      map[models.TourDifficulty]int // zavrsene ture po tezini
+	completedByAuthor map[uint]int                  // zavrsene publishovane ture po autoru
+	publishedByAuthor map[uint]int                  // ukupno publishovanih tura po autoru
+}
+
+// BadgeRule je pravilo za jedan bedz: progress vraca trenutni napredak i cilj, bedz se dobija kad je current >= target
+type BadgeRule struct {
+	Code        string
+	Name        string
+	Description string
+	progress    func(stats *achievementStats) (current, target float64)
+}
+
+var allDifficulties = []models.TourDifficulty{models.Easy, models.Medium, models.Hard, models.Expert}
+
+// BadgeCatalog su svi bedzevi koje korisnik moze da osvoji. Code se cuva u bazi, pa se ne sme menjati.
+var BadgeCatalog = []BadgeRule{
+	{
+		Code:        "first-tour",
+		Name:        "First Steps",
+		Description: "Complete your first tour",
+		progress: func(stats *achievementStats) (float64, float64) {
+			return float64(stats.completedTours), 1
+		},
+	},
+	{
+		Code:        "ten-tours",
+		Name:        "Seasoned Explorer",
+		Description: "Complete 10 different tours",
+		progress: func(stats *achievementStats) (float64, float64) {
+			return float64(stats.completedTours), 10
+		},
+	},
+	{
+		Code:        "all-difficulties",
+		Name:        "All-Rounder",
+		Description: "Complete a tour of every difficulty",
+		progress: func(stats *achievementStats) (float64, float64) {
+			done := 0
+			for _, difficulty := range allDifficulties {
+				if stats.difficulties[difficulty] > 0 {
+					done++
+				}
+			}
+			return float64(done), float64(len(allDifficulties))
+		},
+	},
+	{
+		Code:        "hundred-km",
+		Name:        "Centurion",
+		Description: "Walk 100 km in total on completed tours",
+		progress: func(stats *achievementStats) (float64, float64) {
+			return math.Round(stats.totalKm*100) / 100, 100
+		},
+	},
+	{
+		Code:        "author-complete",
+		Name:        "Devoted Follower",
+		Description: "Complete every published tour of one author (at least 3 tours)",
+		progress: func(stats *achievementStats) (float64, float64) {
+			// napredak je autor kome je korisnik najblizi, po udelu zavrsenih tura
+			bestDone, bestTotal := 0, minAuthorTours
+			for authorID, total := range stats.publishedByAuthor {
+				if total < minAuthorTours {
+					continue
+				}
+				done := stats.completedByAuthor[authorID]
+				if done*bestTotal > bestDone*total {
+					bestDone, bestTotal = done, total
+				}
+			}
+			return float64(bestDone), float64(bestTotal)
+		},
+	},
+}
+
+type BadgeService struct {
+	repo *repository.BadgeRepository
+}
+
+func NewBadgeService(repo *repository.BadgeRepository) *BadgeService {
+	return &BadgeService{repo: repo}
+}
+
+// EvaluateBadges proverava sva pravila za korisnika i cuva bedzeve koje je upravo osvojio.
+// Poziva se kada se izvrsavanje zavrsi; vec osvojeni bedzevi se ne gube i ako napredak kasnije padne.
+func (s *BadgeService) EvaluateBadges(userID uint) ([]models.UserBadge, error) {
+	stats, err := s.achievementStats(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	earned := []models.UserBadge{}
+	now := time.Now()
+	for _, rule := range BadgeCatalog {
+		current, target := rule.progress(stats)
+		if current < target {
+			continue
+		}
+		badge := models.UserBadge{UserID: userID, Code: rule.Code, EarnedAt: now}
+		isNew, err := s.repo.Award(&badge)
+		if err != nil {
+			return earned, err
+		}
+		if isNew {
+			log.Printf("User %d earned badge %s", userID, rule.Code)
+			earned = append(earned, badge)
+		}
+	}
+	return earned, nil
+}
+
+// GetUserBadges vraca bedzeve koje je korisnik osvojio
+func (s *BadgeService) GetUserBadges(userID uint) ([]dto.BadgeResponse, error) {
+	badges, err := s.repo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := []dto.BadgeResponse{}
+	for _, badge := range badges {
+		rule := badgeRule(badge.Code)
+		if rule == nil {
+			// pravilo je uklonjeno iz kataloga
+			continue
+		}
+		response = append(response, dto.BadgeResponse{
+			Code:        rule.Code,
+			Name:        rule.Name,
+			Description: rule.Description,
+			EarnedAt:    badge.EarnedAt,
+		})
+	}
+	return response, nil
+}
+
+// GetBadgeProgress vraca ceo katalog bedzeva sa napretkom korisnika
+func (s *BadgeService) GetBadgeProgress(userID uint) ([]dto.BadgeProgressResponse, error) {
+	stats, err := s.achievementStats(userID)
+	if err != nil {
+		return nil, err
+	}
+	badges, err := s.repo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	earnedAt := make(map[string]time.Time, len(badges))
+	for _, badge := range badges {
+		earnedAt[badge.Code] = badge.EarnedAt
+	}
+
+	response := make([]dto.BadgeProgressResponse, 0, len(BadgeCatalog))
+	for _, rule := range BadgeCatalog {
+		current, target := rule.progress(stats)
+		progress := dto.BadgeProgressResponse{
+			Code:        rule.Code,
+			Name:        rule.Name,
+			Description: rule.Description,
+			Current:     math.Min(current, target),
+			Target:      target,
+		}
+		if t, ok := earnedAt[rule.Code]; ok {
+			progress.Earned = true
+			progress.EarnedAt = &t
+			progress.Current = target
+		}
+		response = append(response, progress)
+	}
+	return response, nil
+}
+
+func (s *BadgeService) achievementStats(userID uint) (*achievementStats, error) {
+	tours, err := s.repo.GetCompletedTours(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &achievementStats{
+		completedTours:    len(tours),
+		difficulties:      make(map[models.TourDifficulty]int),
+		completedByAuthor: make(map[uint]int),
+	}
+	authorIDs := []uint{}
+	for _, tour := range tours {
+		stats.totalKm += tour.Distance * float64(tour.Completions)
+		stats.difficulties[tour.Difficulty]++
+		if tour.Status != models.Published || tour.IsDeleted {
+			continue
+		}
+		if stats.completedByAuthor[tour.AuthorID] == 0 {
+			authorIDs = append(authorIDs, tour.AuthorID)
+		}
+		stats.completedByAuthor[tour.AuthorID]++
+	}
+
+	stats.publishedByAuthor, err = s.repo.CountPublishedToursByAuthor(authorIDs)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func badgeRule(code string) *BadgeRule {
+	for i := range BadgeCatalog {
+		if BadgeCatalog[i].Code == code {
+			return &BadgeCatalog[i]
+		}
+	}
+	return nil
+}
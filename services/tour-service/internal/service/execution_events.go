@@ -0,0 +1,143 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// Tipovi dogadjaja koji se salju klijentu preko live kanala izvrsavanja
+const (
+	EventState            = "state"             // trenutno stanje, salje se pri (ponovnom) povezivanju
+	EventKeyPointReached  = "keypoint_reached"  // turista je stigao do keypointa
+	EventNextKeyPoint     = "next_keypoint"     // sledeci keypoint posle zavrsenog
+	EventCompleteEligible = "complete_eligible" // svi keypointi su zavrseni, tura moze da se zavrsi
+	EventCompleted        = "completed"
+	EventAbandoned        = "abandoned"
+	EventBadgeEarned      = "badge_earned" // zavrsavanjem ture turista je osvojio bedz
+)
+
+const (
+	eventBufferSize     = 50 // poslednji dogadjaji po izvrsavanju, za nastavak posle prekida veze
+	subscriberQueueSize = 16
+	streamIdleTimeout   = time.Hour // bafer bez pretplatnika se posle ovoga brise
+)
+
+// ExecutionEvent je jedan dogadjaj izvrsavanja ture
+type ExecutionEvent struct {
+	ID          uint64      `json:"id"`
+	Type        string      `json:"type"`
+	ExecutionID uint        `json:"executionId"`
+	Data        interface{} `json:"data,omitempty"`
+	Time        time.Time   `json:"time"`
+}
+
+// IsTerminal govori da li dogadjaj zatvara izvrsavanje
+func (e ExecutionEvent) IsTerminal() bool {
+	return e.Type == EventCompleted || e.Type == EventAbandoned
+}
+
+// ExecutionEventHub prosledjuje dogadjaje izvrsavanja pretplacenim live kanalima u okviru jedne instance servisa.
+// Za svako izvrsavanje cuva poslednjih eventBufferSize dogadjaja da klijent posle prekida nastavi od Last-Event-ID.
+type ExecutionEventHub struct {
+	mu      sync.Mutex
+	nextID  uint64
+	streams map[uint]*executionStream
+}
+
+type executionStream struct {
+	recent      []ExecutionEvent
+	subscribers map[chan ExecutionEvent]struct{}
+	lastUsed    time.Time
+}
+
+func NewExecutionEventHub() *ExecutionEventHub {
+	return &ExecutionEventHub{streams: make(map[uint]*executionStream)}
+}
+
+// Publish salje dogadjaj svim pretplatnicima izvrsavanja. Dogadjaji se cuvaju samo za izvrsavanja na koja je
+// neko bio pretplacen. Spor pretplatnik gubi dogadjaj umesto da blokira servis; kada se ponovo poveze dobija stanje.
+func (h *ExecutionEventHub) Publish(executionID uint, eventType string, data interface{}) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stream := h.streams[executionID]
+	if stream == nil {
+		return
+	}
+
+	h.nextID++
+	event := ExecutionEvent{ID: h.nextID, Type: eventType, ExecutionID: executionID, Data: data, Time: time.Now()}
+	stream.lastUsed = event.Time
+	stream.recent = append(stream.recent, event)
+	if len(stream.recent) > eventBufferSize {
+		stream.recent = stream.recent[len(stream.recent)-eventBufferSize:]
+	}
+
+	for ch := range stream.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+
+	// zavrseno izvrsavanje bez pretplatnika vise nema kome da salje
+	if event.IsTerminal() && len(stream.subscribers) == 0 {
+		delete(h.streams, executionID)
+	}
+}
+
+// Subscribe pretplacuje na dogadjaje izvrsavanja. Ako je lastEventID jos u baferu, vraca dogadjaje posle njega
+// i resumed=true; inace klijent treba da dobije trenutno stanje. cancel mora da se pozove kada se veza zatvori.
+func (h *ExecutionEventHub) Subscribe(executionID uint, lastEventID uint64) (missed []ExecutionEvent, resumed bool, events <-chan ExecutionEvent, cancel func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.pruneIdle()
+	stream := h.streams[executionID]
+	if stream == nil {
+		stream = &executionStream{subscribers: make(map[chan ExecutionEvent]struct{})}
+		h.streams[executionID] = stream
+	}
+	stream.lastUsed = time.Now()
+
+	if lastEventID > 0 {
+		for i, event := range stream.recent {
+			if event.ID == lastEventID {
+				missed = append([]ExecutionEvent(nil), stream.recent[i+1:]...)
+				resumed = true
+				break
+			}
+		}
+	}
+
+	ch := make(chan ExecutionEvent, subscriberQueueSize)
+	stream.subscribers[ch] = struct{}{}
+
+	cancel = func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(stream.subscribers, ch)
+		stream.lastUsed = time.Now()
+		if len(stream.subscribers) == 0 && h.streams[executionID] == stream {
+			last := len(stream.recent) - 1
+			if last < 0 || stream.recent[last].IsTerminal() {
+				delete(h.streams, executionID)
+			}
+		}
+	}
+	return missed, resumed, ch, cancel
+}
+
+// pruneIdle brise bafere izvrsavanja na koja dugo niko nije pretplacen (npr. zavrsena na drugoj instanci)
+func (h *ExecutionEventHub) pruneIdle() {
+	cutoff := time.Now().Add(-streamIdleTimeout)
+	for id, stream := range h.streams {
+		if len(stream.subscribers) == 0 && stream.lastUsed.Before(cutoff) {
+			delete(h.streams, id)
+		}
+	}
+}
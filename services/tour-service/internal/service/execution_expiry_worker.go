@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+	"tour-service/internal/models"
+)
+
+const (
+	DefaultInactivityTimeout = 2 * time.Hour
+	DefaultExpiryInterval    = 5 * time.Minute
+	expiryBatchSize          = 100
+)
+
+// InactiveExecutionStore napusta zastarela izvrsavanja; implementira ga TourExecutionRepository
+type InactiveExecutionStore interface {
+	AbandonInactive(cutoff time.Time, now time.Time, limit int) ([]models.TourExecution, error)
+}
+
+// ExecutionExpiryWorker periodicno oznacava kao ABANDONED izvrsavanja bez aktivnosti duze od InactivityTimeout.
+// Vise replika servisa moze da ga pokrece istovremeno: redovi se zakljucavaju sa SKIP LOCKED,
+// pa svaku zastarelu turu obradjuje tacno jedna replika.
+type ExecutionExpiryWorker struct {
+	Repo              InactiveExecutionStore
+	Events            *ExecutionEventHub
+	InactivityTimeout time.Duration
+	Interval          time.Duration
+	Now               func() time.Time // sat se moze zameniti u testovima
+}
+
+func NewExecutionExpiryWorker(repo InactiveExecutionStore, events *ExecutionEventHub, inactivityTimeout, interval time.Duration) *ExecutionExpiryWorker {
+	if inactivityTimeout <= 0 {
+		inactivityTimeout = DefaultInactivityTimeout
+	}
+	if interval <= 0 {
+		interval = DefaultExpiryInterval
+	}
+	return &ExecutionExpiryWorker{
+		Repo:              repo,
+		Events:            events,
+		InactivityTimeout: inactivityTimeout,
+		Interval:          interval,
+		Now:               time.Now,
+	}
+}
+
+// Run pokrece proveru na svakih Interval dok se ctx ne otkaze
+func (w *ExecutionExpiryWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.AbandonInactive(); err != nil {
+			log.Printf("Failed to abandon inactive tour executions: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// AbandonInactive u serijama napusta sva zastarela izvrsavanja i vraca njihov broj
+func (w *ExecutionExpiryWorker) AbandonInactive() (int, error) {
+	now := w.Now()
+	cutoff := now.Add(-w.InactivityTimeout)
+
+	total := 0
+	for {
+		abandoned, err := w.Repo.AbandonInactive(cutoff, now, expiryBatchSize)
+		if err != nil {
+			return total, err
+		}
+		total += len(abandoned)
+		for _, execution := range abandoned {
+			log.Printf("Tour execution %d (tour %d, tourist %d) abandoned after inactivity since %s",
+				execution.ID, execution.TourID, execution.TouristID, execution.LastActivity.Format(time.RFC3339))
+			w.Events.Publish(execution.ID, EventAbandoned, map[string]interface{}{"autoAbandoned": true})
+		}
+		if len(abandoned) < expiryBatchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"tour-service/internal/models"
+)
+
+// fakeExecutionStore oponasa TourExecutionRepository.AbandonInactive nad izvrsavanjima u memoriji
+type fakeExecutionStore struct {
+	executions []*models.TourExecution
+	calls      int
+	cutoffs    []time.Time
+	err        error
+}
+
+func (f *fakeExecutionStore) AbandonInactive(cutoff time.Time, now time.Time, limit int) ([]models.TourExecution, error) {
+	f.calls++
+	f.cutoffs = append(f.cutoffs, cutoff)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	var abandoned []models.TourExecution
+	for _, execution := range f.executions {
+		if len(abandoned) == limit {
+			break
+		}
+		if execution.Status != models.ExecutionStarted || !execution.LastActivity.Before(cutoff) {
+			continue
+		}
+		execution.Status = models.ExecutionAbandoned
+		execution.EndTime = &now
+		execution.AutoAbandoned = true
+		abandoned = append(abandoned, *execution)
+	}
+	return abandoned, nil
+}
+
+func startedExecution(id uint, lastActivity time.Time) *models.TourExecution {
+	return &models.TourExecution{ID: id, Status: models.ExecutionStarted, LastActivity: lastActivity}
+}
+
+func newTestExpiryWorker(store InactiveExecutionStore, now time.Time) *ExecutionExpiryWorker {
+	worker := NewExecutionExpiryWorker(store, NewExecutionEventHub(), 2*time.Hour, time.Minute)
+	worker.Now = func() time.Time { return now }
+	return worker
+}
+
+func TestAbandonInactiveUsesInjectedClock(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	stale := startedExecution(1, now.Add(-3*time.Hour))
+	// turista koji hoda izmedju udaljenih keypointa i salje pozicije nije neaktivan
+	walking := startedExecution(2, now.Add(-30*time.Minute))
+	completed := &models.TourExecution{ID: 3, Status: models.ExecutionCompleted, LastActivity: now.Add(-5 * time.Hour)}
+	store := &fakeExecutionStore{executions: []*models.TourExecution{stale, walking, completed}}
+
+	total, err := newTestExpiryWorker(store, now).AbandonInactive()
+	if err != nil {
+		t.Fatalf("AbandonInactive returned error: %v", err)
+	}
+	if total != 1 {
+		t.Fatalf("expected 1 abandoned execution, got %d", total)
+	}
+	if want := now.Add(-2 * time.Hour); !store.cutoffs[0].Equal(want) {
+		t.Errorf("expected cutoff %v, got %v", want, store.cutoffs[0])
+	}
+	if stale.Status != models.ExecutionAbandoned || !stale.AutoAbandoned || !stale.EndTime.Equal(now) {
+		t.Errorf("stale execution not abandoned at injected time: %+v", stale)
+	}
+	if walking.Status != models.ExecutionStarted {
+		t.Errorf("recently active execution was abandoned")
+	}
+	if completed.Status != models.ExecutionCompleted {
+		t.Errorf("completed execution was changed")
+	}
+}
+
+func TestAbandonInactiveProcessesAllBatches(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := &fakeExecutionStore{}
+	for i := 0; i < 2*expiryBatchSize+5; i++ {
+		store.executions = append(store.executions, startedExecution(uint(i+1), now.Add(-3*time.Hour)))
+	}
+
+	total, err := newTestExpiryWorker(store, now).AbandonInactive()
+	if err != nil {
+		t.Fatalf("AbandonInactive returned error: %v", err)
+	}
+	if total != 2*expiryBatchSize+5 {
+		t.Errorf("expected %d abandoned executions, got %d", 2*expiryBatchSize+5, total)
+	}
+	if store.calls != 3 {
+		t.Errorf("expected 3 batches, got %d", store.calls)
+	}
+}
+
+func TestAbandonInactiveStopsOnStoreError(t *testing.T) {
+	store := &fakeExecutionStore{err: errors.New("db down")}
+
+	if _, err := newTestExpiryWorker(store, time.Now()).AbandonInactive(); err == nil {
+		t.Fatal("expected store error to be returned")
+	}
+	if store.calls != 1 {
+		t.Errorf("expected a single attempt, got %d", store.calls)
+	}
+}
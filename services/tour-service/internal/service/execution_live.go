@@ -0,0 +1,90 @@
+package service
+
+import (
+	"errors"
+	"tour-service/internal/dto"
+	"tour-service/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ExecutionSubscription je otvoren live kanal jednog izvrsavanja
+type ExecutionSubscription struct {
+	State  *dto.ExecutionStateResponse // trenutno stanje, nil kada se nastavlja od Last-Event-ID
+	Missed []ExecutionEvent            // dogadjaji propusteni tokom prekida veze
+	Events <-chan ExecutionEvent
+	Cancel func()
+}
+
+// SubscribeExecution otvara live kanal izvrsavanja za turistu koji ga obilazi. Ako su dogadjaji posle
+// lastEventID jos sacuvani, vraca njih; inace vraca trenutno stanje izvrsavanja.
+func (s *TourExecutionService) SubscribeExecution(executionID uint, touristID uint, lastEventID uint64) (*ExecutionSubscription, error) {
+	execution, err := s.repo.GetByID(executionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrExecutionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if execution.TouristID != touristID {
+		return nil, ErrNotExecutionOwner
+	}
+
+	// pretplata pre citanja stanja, da se nista ne izgubi izmedju
+	missed, resumed, events, cancel := s.events.Subscribe(executionID, lastEventID)
+	subscription := &ExecutionSubscription{Missed: missed, Events: events, Cancel: cancel}
+	if resumed {
+		return subscription, nil
+	}
+
+	subscription.State, err = s.executionState(executionID)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// executionState vraca izvrsavanje sa sledecim keypointom i udaljenoscu do njega od poslednje pozicije
+func (s *TourExecutionService) executionState(executionID uint) (*dto.ExecutionStateResponse, error) {
+	execution, err := s.repo.GetByID(executionID)
+	if err != nil {
+		return nil, err
+	}
+	tour, err := tourForExecution(s.repo, execution)
+	if err != nil {
+		return nil, err
+	}
+	last, err := s.repo.GetLastTrackPoint(executionID)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &dto.ExecutionStateResponse{Execution: execution}
+	if execution.Status != models.ExecutionStarted {
+		return state, nil
+	}
+
+	mode := completionModeOf(tour)
+	lat, lng := execution.StartingLatitude, execution.StartingLongitude
+	if last != nil {
+		lat, lng = last.Latitude, last.Longitude
+	}
+	next, distance := nextKeyPoint(tour.KeyPoints, execution.CompletedKeyPoints, mode, lat, lng)
+	if next != nil {
+		state.NextKeyPoint = next
+		state.DistanceToNextKm = &distance
+	}
+	state.CompleteEligible = len(tour.KeyPoints) > 0 && completedCount(tour.KeyPoints, execution.CompletedKeyPoints) == len(tour.KeyPoints)
+	return state, nil
+}
+
+// ExecutionStatus vraca trenutni status izvrsavanja, live kanal ga proverava uz heartbeat
+// jer izvrsavanje moze da zavrsi i druga instanca servisa
+func (s *TourExecutionService) ExecutionStatus(executionID uint) (models.TourExecutionStatus, error) {
+	execution, err := s.repo.GetByID(executionID)
+	if err != nil {
+		return "", err
+	}
+	return execution.Status, nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"tour-service/internal/dto"
+	"tour-service/internal/models"
+	"tour-service/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// GetRevealedSecrets vraca tajni sadrzaj svih keypointa do kojih je turista stigao u izvrsavanju
+func (s *TourExecutionService) GetRevealedSecrets(executionID uint, touristID uint) ([]dto.RevealedKeyPoint, error) {
+	execution, err := s.repo.GetByID(executionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrExecutionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if execution.TouristID != touristID {
+		return nil, ErrNotExecutionOwner
+	}
+
+	tour, err := tourForExecution(s.repo, execution)
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := executionSecrets(s.repo, execution)
+	if err != nil {
+		return nil, err
+	}
+	return revealSecrets(tour.KeyPoints, secrets, func(id uint) bool {
+		return contains(execution.CompletedKeyPoints, int64(id))
+	}), nil
+}
+
+// revealNewlyCompleted vraca tajne keypointa koji su upravo zavrseni
+func revealNewlyCompleted(repo *repository.TourExecutionRepository, execution *models.TourExecution, tour *models.Tour, newlyCompleted []int) ([]dto.RevealedKeyPoint, error) {
+	if len(newlyCompleted) == 0 {
+		return nil, nil
+	}
+	secrets, err := executionSecrets(repo, execution)
+	if err != nil {
+		return nil, err
+	}
+	return revealSecrets(tour.KeyPoints, secrets, func(id uint) bool {
+		for _, completed := range newlyCompleted {
+			if uint(completed) == id {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// executionSecrets vraca tajne keypointa verzije ture na kojoj je izvrsavanje zapoceto
+func executionSecrets(repo *repository.TourExecutionRepository, execution *models.TourExecution) (map[uint]*models.KeyPointSecret, error) {
+	if execution.TourVersion > 0 {
+		tour, err := repo.GetTourByVersion(execution.TourID, execution.TourVersion)
+		if err == nil {
+			secrets := make(map[uint]*models.KeyPointSecret, len(tour.KeyPoints))
+			for _, kp := range tour.KeyPoints {
+				if kp.Secret != nil {
+					secrets[kp.ID] = kp.Secret
+				}
+			}
+			return secrets, nil
+		}
+		log.Printf("Tour %d version %d not found, using current key point secrets: %v", execution.TourID, execution.TourVersion, err)
+	}
+	return repository.NewKeyPointRepository(repo.DB).FindSecretsByTourID(execution.TourID)
+}
+
+// revealSecrets vraca tajne keypointa (po redosledu ture) za koje reached vraca true
+func revealSecrets(keyPoints []models.KeyPoint, secrets map[uint]*models.KeyPointSecret, reached func(id uint) bool) []dto.RevealedKeyPoint {
+	revealed := []dto.RevealedKeyPoint{}
+	for _, kp := range keyPoints {
+		secret := secrets[kp.ID]
+		if secret == nil || !reached(kp.ID) {
+			continue
+		}
+		revealed = append(revealed, dto.RevealedKeyPoint{
+			KeyPointID: kp.ID,
+			Name:       kp.Name,
+			Order:      kp.Order,
+			Text:       secret.Text,
+			Images:     secret.Images,
+			AudioURL:   secret.AudioURL,
+		})
+	}
+	return revealed
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"tour-service/internal/dto"
+	"tour-service/internal/models"
+	"tour-service/internal/repository"
+)
+
+const (
+	MaxSyncPositions = 5000
+	// koliko sat telefona sme da zuri u odnosu na server
+	syncClockSkew = time.Minute
+)
+
+// SyncPositions ponavlja pozicije snimljene offline kroz istu logiku zavrsavanja keypointa kao CheckPosition,
+// po redosledu vremena snimanja. Ponovo poslat batch ne menja nista: vec primljene pozicije se preskacu,
+// a keypoint se zavrsava samo jednom. Pozicije van prozora izvrsavanja (pre pocetka, posle kraja ili u
+// buducnosti) se odbijaju pojedinacno.
+//
+// Izvrsavanje koje je worker napustio zbog neaktivnosti prima pozicije snimljene do trenutka napustanja,
+// jer turista je mozda samo bio bez signala. Napredak (keypointi i putanja) se cuva, ali status ostaje
+// ABANDONED - iz zavrsnog stanja se ne izlazi. Zavrsena ili rucno napustena izvrsavanja ne primaju batch.
+func (s *TourExecutionService) SyncPositions(executionID uint, touristID uint, req dto.SyncPositionsRequest) (*dto.SyncPositionsResponse, error) {
+	if len(req.Positions) == 0 {
+		return nil, errors.New("no positions to sync")
+	}
+	if len(req.Positions) > MaxSyncPositions {
+		return nil, fmt.Errorf("too many positions, at most %d per batch", MaxSyncPositions)
+	}
+
+	var response *dto.SyncPositionsResponse
+	err := s.withOwnedExecution(executionID, touristID, func(repo *repository.TourExecutionRepository, execution *models.TourExecution) error {
+		windowEnd := time.Now().Add(syncClockSkew)
+		switch {
+		case execution.Status == models.ExecutionStarted:
+		case execution.Status == models.ExecutionAbandoned && execution.AutoAbandoned && execution.EndTime != nil:
+			windowEnd = *execution.EndTime
+		default:
+			return fmt.Errorf("%w: execution is %s", ErrExecutionConflict, execution.Status)
+		}
+
+		accepted, duplicates, rejected, err := filterSyncedPositions(repo, execution, req.Positions, windowEnd)
+		if err != nil {
+			return err
+		}
+
+		tour, err := tourForExecution(repo, execution)
+		if err != nil {
+			return err
+		}
+
+		newlyCompleted := []int{}
+		trackPoints := make([]models.ExecutionTrackPoint, 0, len(accepted))
+		for _, p := range accepted {
+			trackPoints = append(trackPoints, models.ExecutionTrackPoint{
+				ExecutionID: execution.ID,
+				Latitude:    p.Latitude,
+				Longitude:   p.Longitude,
+				RecordedAt:  p.RecordedAt,
+			})
+			newlyCompleted = append(newlyCompleted, completeKeyPointsAt(execution, tour, p.Latitude, p.Longitude)...)
+		}
+		if err := repo.CreateTrackPoints(trackPoints); err != nil {
+			return err
+		}
+
+		if execution.Status == models.ExecutionStarted {
+			execution.LastActivity = time.Now()
+		}
+		if len(accepted) > 0 {
+			if _, err := repo.Update(execution); err != nil {
+				return err
+			}
+		}
+
+		lat, lng := execution.StartingLatitude, execution.StartingLongitude
+		if last, err := repo.GetLastTrackPoint(execution.ID); err == nil && last != nil {
+			lat, lng = last.Latitude, last.Longitude
+		}
+
+		response = &dto.SyncPositionsResponse{
+			CheckPositionResponse: *positionResponse(execution, tour, newlyCompleted, lat, lng),
+			Accepted:              len(accepted),
+			Duplicates:            duplicates,
+			Rejected:              rejected,
+			ExecutionStatus:       string(execution.Status),
+		}
+		response.Revealed, err = revealNewlyCompleted(repo, execution, tour, newlyCompleted)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if response.ExecutionStatus == string(models.ExecutionStarted) {
+		s.publishProgress(executionID, &response.CheckPositionResponse)
+	}
+	return response, nil
+}
+
+// filterSyncedPositions sortira pozicije po vremenu, odbacuje one van prozora [pocetak, windowEnd]
+// i preskace one koje su vec primljene (u bazi ili ranije u istom batchu)
+func filterSyncedPositions(repo *repository.TourExecutionRepository, execution *models.TourExecution, positions []dto.SyncedPosition, windowEnd time.Time) ([]dto.SyncedPosition, int, []dto.RejectedPosition, error) {
+	type indexed struct {
+		index    int
+		position dto.SyncedPosition
+	}
+	ordered := make([]indexed, len(positions))
+	for i, p := range positions {
+		// Postgres cuva vreme u mikrosekundama, pa se tako i porede ponovljene pozicije
+		p.RecordedAt = p.RecordedAt.UTC().Truncate(time.Microsecond)
+		ordered[i] = indexed{index: i, position: p}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].position.RecordedAt.Before(ordered[j].position.RecordedAt)
+	})
+
+	rejected := []dto.RejectedPosition{}
+	var inWindow []indexed
+	for _, item := range ordered {
+		p := item.position
+		reason := ""
+		switch {
+		case p.RecordedAt.IsZero():
+			reason = "missing recordedAt"
+		case p.Latitude < -90 || p.Latitude > 90 || p.Longitude < -180 || p.Longitude > 180:
+			reason = "invalid coordinates"
+		case p.RecordedAt.Before(execution.StartTime):
+			reason = "recorded before the execution started"
+		case p.RecordedAt.After(windowEnd):
+			reason = "recorded after the execution ended"
+		}
+		if reason != "" {
+			rejected = append(rejected, dto.RejectedPosition{Index: item.index, RecordedAt: p.RecordedAt, Reason: reason})
+			continue
+		}
+		inWindow = append(inWindow, item)
+	}
+	if len(inWindow) == 0 {
+		return nil, 0, rejected, nil
+	}
+
+	known, err := repo.GetTrackPointTimes(execution.ID, inWindow[0].position.RecordedAt, inWindow[len(inWindow)-1].position.RecordedAt)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	seen := make(map[int64]bool, len(known)+len(inWindow))
+	for _, t := range known {
+		seen[t.UnixMicro()] = true
+	}
+
+	accepted := make([]dto.SyncedPosition, 0, len(inWindow))
+	duplicates := 0
+	for _, item := range inWindow {
+		key := item.position.RecordedAt.UnixMicro()
+		if seen[key] {
+			duplicates++
+			continue
+		}
+		seen[key] = true
+		accepted = append(accepted, item.position)
+	}
+	return accepted, duplicates, rejected, nil
+}
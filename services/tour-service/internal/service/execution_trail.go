@@ -0,0 +1,139 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+	"tour-service/internal/dto"
+	"tour-service/internal/models"
+)
+
+const (
+	// deonice sporije od ovoga su GPS sum dok turista stoji i ne racunaju se u predjeni put
+	minMovingSpeedKmh = 1.0
+	// deonice brze od ovoga su skokovi GPS-a, ne stvarno kretanje
+	maxMovingSpeedKmh = 150.0
+)
+
+// GetTrail vraca predjenu putanju izvrsavanja u GeoJSON ili GPX formatu.
+// Vidi je turista koji je obilazi, autor ture i administrator.
+func (s *TourExecutionService) GetTrail(executionID uint, userID uint, isAdmin bool, format RouteFormat) ([]byte, error) {
+	if format != FormatGeoJSON && format != FormatGPX {
+		return nil, fmt.Errorf("unsupported trail format: %s", format)
+	}
+
+	execution, points, err := s.trailForViewer(executionID, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	stats := ComputeTrailStats(execution.ID, points)
+
+	if format == FormatGPX {
+		return renderTrailGPX(execution, points)
+	}
+	return renderTrailGeoJSON(execution, points, stats)
+}
+
+// GetTrailStats vraca predjeni put, vreme kretanja i prosecnu brzinu izvrsavanja
+func (s *TourExecutionService) GetTrailStats(executionID uint, userID uint, isAdmin bool) (*dto.TrailStatsResponse, error) {
+	execution, points, err := s.trailForViewer(executionID, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return ComputeTrailStats(execution.ID, points), nil
+}
+
+func (s *TourExecutionService) trailForViewer(executionID uint, userID uint, isAdmin bool) (*models.TourExecution, []models.ExecutionTrackPoint, error) {
+	execution, err := s.executionForViewer(executionID, userID, isAdmin)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	points, err := s.repo.GetTrackPoints(executionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return execution, points, nil
+}
+
+// ComputeTrailStats racuna statistiku iz tacaka sortiranih po vremenu. U predjeni put i vreme kretanja
+// ulaze samo deonice cija je brzina realna za kretanje, da GPS sum u mestu i skokovi ne bi kvarili rezultat.
+func ComputeTrailStats(executionID uint, points []models.ExecutionTrackPoint) *dto.TrailStatsResponse {
+	stats := &dto.TrailStatsResponse{ExecutionID: executionID, PointCount: len(points)}
+	if len(points) == 0 {
+		return stats
+	}
+
+	start, end := points[0].RecordedAt, points[len(points)-1].RecordedAt
+	stats.StartedAt, stats.EndedAt = &start, &end
+	stats.ElapsedSeconds = int64(end.Sub(start).Seconds())
+
+	var moving time.Duration
+	for i := 1; i < len(points); i++ {
+		elapsed := points[i].RecordedAt.Sub(points[i-1].RecordedAt)
+		if elapsed <= 0 {
+			continue
+		}
+		distance := calculateDistance(points[i-1].Latitude, points[i-1].Longitude, points[i].Latitude, points[i].Longitude)
+		speed := distance / elapsed.Hours()
+		if speed < minMovingSpeedKmh || speed > maxMovingSpeedKmh {
+			continue
+		}
+		stats.DistanceKm += distance
+		moving += elapsed
+	}
+
+	stats.MovingTimeSeconds = int64(moving.Seconds())
+	if moving > 0 {
+		stats.AverageSpeedKmh = stats.DistanceKm / moving.Hours()
+	}
+	return stats
+}
+
+func renderTrailGPX(execution *models.TourExecution, points []models.ExecutionTrackPoint) ([]byte, error) {
+	name := fmt.Sprintf("Tour %d execution %d", execution.TourID, execution.ID)
+	doc := gpxDocument{
+		Version:  "1.1",
+		Creator:  "tour-service",
+		XMLNS:    "http://www.topografix.com/GPX/1/1",
+		Metadata: &gpxMetadata{Name: name},
+	}
+
+	segment := gpxTrackSegment{}
+	for _, p := range points {
+		segment.Points = append(segment.Points, gpxPoint{
+			Latitude:  formatCoordinate(p.Latitude),
+			Longitude: formatCoordinate(p.Longitude),
+			Time:      p.RecordedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	doc.Tracks = []gpxTrack{{Name: name, Segments: []gpxTrackSegment{segment}}}
+
+	return marshalXML(doc)
+}
+
+func renderTrailGeoJSON(execution *models.TourExecution, points []models.ExecutionTrackPoint, stats *dto.TrailStatsResponse) ([]byte, error) {
+	line := make([][2]float64, 0, len(points))
+	times := make([]string, 0, len(points))
+	for _, p := range points {
+		line = append(line, [2]float64{p.Longitude, p.Latitude})
+		times = append(times, p.RecordedAt.UTC().Format(time.RFC3339))
+	}
+	coordinates, _ := json.Marshal(line)
+
+	collection := geoJSONFeatureCollection{Type: "FeatureCollection", Features: []geoJSONFeature{{
+		Type:     "Feature",
+		Geometry: &geoJSONGeometry{Type: "LineString", Coordinates: coordinates},
+		Properties: map[string]interface{}{
+			"executionId":       execution.ID,
+			"tourId":            execution.TourID,
+			"status":            execution.Status,
+			"times":             times,
+			"distanceKm":        stats.DistanceKm,
+			"movingTimeSeconds": stats.MovingTimeSeconds,
+			"averageSpeedKmh":   stats.AverageSpeedKmh,
+		},
+	}}}
+
+	return json.Marshal(collection)
+}
@@ -2,20 +2,30 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"tour-service/internal/dto"
 	"tour-service/internal/models"
 	"tour-service/internal/repository"
+
+	"gorm.io/gorm"
 )
 
+// publishovane ture su kupljene u tacno odredjenom obliku, pa se menjaju samo preko draft revizije
+var ErrNotDraft = errors.New("only draft tours can be edited, create a revision of a published tour")
+
+var ErrKeyPointNotFound = errors.New("key point not found")
+
 type KeyPointService struct {
 	KeyPointRepo *repository.KeyPointRepository
 	TourRepo     *repository.TourRepository
+	Routes       *RoutePlanner
 }
 
-func NewKeyPointService(keyPointRepo *repository.KeyPointRepository, tourRepo *repository.TourRepository) *KeyPointService {
+func NewKeyPointService(keyPointRepo *repository.KeyPointRepository, tourRepo *repository.TourRepository, routes *RoutePlanner) *KeyPointService {
 	return &KeyPointService{
 		KeyPointRepo: keyPointRepo,
 		TourRepo:     tourRepo,
+		Routes:       routes,
 	}
 }
 
@@ -24,126 +34,339 @@ func (s *KeyPointService) GetKeyPointsByTour(tourID uint) ([]models.KeyPoint, er
 	return s.KeyPointRepo.FindByTourID(tourID)
 }
 
-// UpdateKeyPoint updatuje postojeci keypoint
+// UpdateKeyPoint updatuje postojeci keypoint; polja, tajna i redosled se menjaju u jednoj transakciji
+// nad zakljucanom turom, pa istovremena izmena ili publish ture ne vide delimicnu izmenu
 func (s *KeyPointService) UpdateKeyPoint(keyPointID uint, authorID uint, req dto.UpdateKeyPointRequest) (*models.KeyPoint, error) {
-	// Get the key point
-	keyPoint, err := s.KeyPointRepo.FindByID(keyPointID)
-	if err != nil {
-		return nil, errors.New("key point not found")
+	if err := validateArrivalRadius(req.ArrivalRadius); err != nil {
+		return nil, err
 	}
 
-	// Verifikuj da autor poseduje turu
-	tours, err := s.TourRepo.FindByAuthorID(authorID)
+	keyPoint, err := s.KeyPointRepo.FindByID(keyPointID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrKeyPointNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	var hasPermission bool
-	for _, tour := range tours {
-		if tour.ID == keyPoint.TourID {
-			hasPermission = true
-			break
+	keyPoints, err := s.modifyKeyPoints(keyPoint.TourID, authorID, func(keyPoints []models.KeyPoint) ([]models.KeyPoint, error) {
+		index := indexOfKeyPoint(keyPoints, keyPointID)
+		if index < 0 {
+			return nil, ErrKeyPointNotFound
 		}
-	}
+		updated := keyPoints[index]
 
-	if !hasPermission {
-		return nil, errors.New("you don't have permission to modify this key point")
-	}
+		// Update polja
+		if req.Name != "" {
+			updated.Name = req.Name
+		}
+		if req.Description != "" {
+			updated.Description = req.Description
+		}
+		if req.Latitude != 0 {
+			updated.Latitude = req.Latitude
+		}
+		if req.Longitude != 0 {
+			updated.Longitude = req.Longitude
+		}
+		if req.Image != "" {
+			updated.Image = req.Image
+		}
+		if req.ArrivalRadius != 0 {
+			updated.ArrivalRadius = req.ArrivalRadius
+		}
+		if req.Secret != nil {
+			secret := secretValue(req.Secret)
+			updated.Secret = &secret
+		}
 
-	// Update polja
-	if req.Name != "" {
-		keyPoint.Name = req.Name
+		// promena redosleda pomera keypoint i renumerise ostale, da ne bi bilo duplih ili preskocenih Order vrednosti
+		keyPoints = append(keyPoints[:index], keyPoints[index+1:]...)
+		position := index + 1
+		if req.Order != 0 {
+			position = req.Order
+		}
+		return insertKeyPoint(keyPoints, updated, position), nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	if req.Description != "" {
-		keyPoint.Description = req.Description
+	return &keyPoints[indexOfKeyPoint(keyPoints, keyPointID)], nil
+}
+
+// Obrisi keypoint
+func (s *KeyPointService) DeleteKeyPoint(keyPointID uint, authorID uint) error {
+	// Get key point da bi se znala tura, vlasnistvo proverava modifyKeyPoints
+	keyPoint, err := s.KeyPointRepo.FindByID(keyPointID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrKeyPointNotFound
 	}
-	if req.Latitude != 0 {
-		keyPoint.Latitude = req.Latitude
+	if err != nil {
+		return err
 	}
-	if req.Longitude != 0 {
-		keyPoint.Longitude = req.Longitude
+
+	// Brise keypoint, renumerise preostale i rekalkulise distancu
+	_, err = s.modifyKeyPoints(keyPoint.TourID, authorID, func(keyPoints []models.KeyPoint) ([]models.KeyPoint, error) {
+		index := indexOfKeyPoint(keyPoints, keyPointID)
+		if index < 0 {
+			return nil, ErrKeyPointNotFound
+		}
+		return append(keyPoints[:index], keyPoints[index+1:]...), nil
+	})
+	return err
+}
+
+// AppendKeyPoint dodaje keypoint na kraj ture
+func (s *KeyPointService) AppendKeyPoint(tourID uint, authorID uint, req dto.CreateKeyPointRequest) (*models.KeyPoint, error) {
+	req.Order = 0
+	return s.InsertKeyPoint(tourID, authorID, req)
+}
+
+// InsertKeyPoint dodaje keypoint na poziciju req.Order (1-based), ostali se pomeraju;
+// pozicija 0 ili veca od broja keypointa dodaje na kraj
+func (s *KeyPointService) InsertKeyPoint(tourID uint, authorID uint, req dto.CreateKeyPointRequest) (*models.KeyPoint, error) {
+	if err := validateKeyPointRequest(req); err != nil {
+		return nil, err
 	}
-	if req.Image != "" {
-		keyPoint.Image = req.Image
+	if req.Order < 0 {
+		return nil, fmt.Errorf("%w: position cannot be negative", ErrInvalidRequest)
 	}
-	if req.Order != 0 {
-		keyPoint.Order = req.Order
+
+	newKeyPoint := models.KeyPoint{
+		TourID:        tourID,
+		Name:          req.Name,
+		Description:   req.Description,
+		Latitude:      req.Latitude,
+		Longitude:     req.Longitude,
+		Image:         req.Image,
+		ArrivalRadius: arrivalRadiusOrDefault(req.ArrivalRadius),
+		Secret:        secretFromRequest(req.Secret),
 	}
 
-	err = s.KeyPointRepo.Update(keyPoint)
+	var insertedAt int
+	keyPoints, err := s.modifyKeyPoints(tourID, authorID, func(keyPoints []models.KeyPoint) ([]models.KeyPoint, error) {
+		position := req.Order
+		if position == 0 || position > len(keyPoints) {
+			position = len(keyPoints) + 1
+		}
+		insertedAt = position - 1
+		return insertKeyPoint(keyPoints, newKeyPoint, position), nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Recalkulise distancu posle azuriranja keypointa (ako se koordinate menjaju)
-	if req.Latitude != 0 || req.Longitude != 0 {
-		s.calculateAndUpdateDistance(keyPoint.TourID)
-	}
+	return &keyPoints[insertedAt], nil
+}
 
-	return keyPoint, nil
+// ReorderKeyPoints atomicno postavlja novi redosled svih keypointa ture
+func (s *KeyPointService) ReorderKeyPoints(tourID uint, authorID uint, req dto.ReorderKeyPointsRequest) ([]models.KeyPoint, error) {
+	return s.modifyKeyPoints(tourID, authorID, func(keyPoints []models.KeyPoint) ([]models.KeyPoint, error) {
+		if len(req.KeyPointIDs) != len(keyPoints) {
+			return nil, fmt.Errorf("%w: expected %d key point IDs, got %d", ErrInvalidRequest, len(keyPoints), len(req.KeyPointIDs))
+		}
+
+		byID := make(map[uint]models.KeyPoint, len(keyPoints))
+		for _, kp := range keyPoints {
+			byID[kp.ID] = kp
+		}
+
+		reordered := make([]models.KeyPoint, 0, len(keyPoints))
+		for _, id := range req.KeyPointIDs {
+			kp, ok := byID[id]
+			if !ok {
+				return nil, fmt.Errorf("%w: key point %d is missing, duplicated or does not belong to this tour", ErrInvalidRequest, id)
+			}
+			delete(byID, id)
+			reordered = append(reordered, kp)
+		}
+		return reordered, nil
+	})
 }
 
-// Obrisi keypoint
-func (s *KeyPointService) DeleteKeyPoint(keyPointID uint, authorID uint) error {
-	// Get key point da proveri vlasnistvo
-	keyPoint, err := s.KeyPointRepo.FindByID(keyPointID)
+// modifyKeyPoints u jednoj transakciji zakljucava turu, proverava autora, primenjuje izmenu liste keypointa,
+// brise izbacene, cuva nove i renumerise Order od 1 bez rupa, a zatim preracunava rutu ture
+func (s *KeyPointService) modifyKeyPoints(tourID uint, authorID uint, modify func([]models.KeyPoint) ([]models.KeyPoint, error)) ([]models.KeyPoint, error) {
+	err := s.KeyPointRepo.DB.Transaction(func(tx *gorm.DB) error {
+		tourRepo := repository.NewTourRepository(tx)
+		keyPointRepo := repository.NewKeyPointRepository(tx)
+
+		tour, err := tourRepo.FindByIDForUpdate(tourID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTourNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if tour.AuthorID != authorID {
+			return ErrNotTourAuthor
+		}
+		if tour.Status != models.Draft {
+			return ErrNotDraft
+		}
+
+		current, err := keyPointRepo.FindByTourID(tourID)
+		if err != nil {
+			return err
+		}
+
+		modified, err := modify(append([]models.KeyPoint(nil), current...))
+		if err != nil {
+			return err
+		}
+
+		kept := make(map[uint]bool, len(modified))
+		for _, kp := range modified {
+			if kp.ID != 0 {
+				kept[kp.ID] = true
+			}
+		}
+		for _, kp := range current {
+			if !kept[kp.ID] {
+				if err := keyPointRepo.Delete(kp.ID); err != nil {
+					return err
+				}
+			}
+		}
+
+		for i := range modified {
+			modified[i].TourID = tourID
+			modified[i].Order = i + 1
+			if modified[i].ID == 0 {
+				err = keyPointRepo.Create(&modified[i])
+			} else {
+				// tajna postojeceg keypointa se menja samo kada je izmena postavi
+				secret := modified[i].Secret
+				modified[i].Secret = nil
+				if err = keyPointRepo.Update(&modified[i]); err == nil && secret != nil {
+					err = keyPointRepo.SaveSecret(modified[i].ID, *secret)
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 	if err != nil {
-		return errors.New("key point not found")
+		return nil, err
 	}
 
-	// Verifikuj da autor poseduje turu
-	tours, err := s.TourRepo.FindByAuthorID(authorID)
-	if err != nil {
-		return err
+	// ruta se racuna posle commit-a da HTTP poziv routing servisu ne bi drzao lock nad turom
+	if err := s.calculateAndUpdateDistance(tourID); err != nil {
+		return nil, err
 	}
+	return s.KeyPointRepo.FindByTourID(tourID)
+}
 
-	var hasPermission bool
-	for _, tour := range tours {
-		if tour.ID == keyPoint.TourID {
-			hasPermission = true
-			break
-		}
+// insertKeyPoint ubacuje keypoint na poziciju (1-based), van opsega znaci na pocetak ili kraj
+func insertKeyPoint(keyPoints []models.KeyPoint, keyPoint models.KeyPoint, position int) []models.KeyPoint {
+	index := position - 1
+	if index < 0 {
+		index = 0
+	}
+	if index > len(keyPoints) {
+		index = len(keyPoints)
 	}
+	keyPoints = append(keyPoints, models.KeyPoint{})
+	copy(keyPoints[index+1:], keyPoints[index:])
+	keyPoints[index] = keyPoint
+	return keyPoints
+}
 
-	if !hasPermission {
-		return errors.New("you don't have permission to delete this key point")
+func indexOfKeyPoint(keyPoints []models.KeyPoint, id uint) int {
+	for i, kp := range keyPoints {
+		if kp.ID == id {
+			return i
+		}
 	}
+	return -1
+}
 
-	tourID := keyPoint.TourID
-	err = s.KeyPointRepo.Delete(keyPointID)
-	if err != nil {
-		return err
+func validateKeyPointRequest(req dto.CreateKeyPointRequest) error {
+	if req.Name == "" {
+		return fmt.Errorf("%w: key point name is required", ErrInvalidRequest)
+	}
+	if req.Latitude < -90 || req.Latitude > 90 || req.Longitude < -180 || req.Longitude > 180 {
+		return fmt.Errorf("%w: invalid key point coordinates", ErrInvalidRequest)
 	}
+	return validateArrivalRadius(req.ArrivalRadius)
+}
 
-	// Rekalkulisi distancu posle brisanja keypointa
-	s.calculateAndUpdateDistance(tourID)
+const (
+	DefaultArrivalRadius = 50.0 // metri
+	minArrivalRadius     = 5.0
+	maxArrivalRadius     = 1000.0
+)
 
+// validateArrivalRadius proverava radius dolaska u metrima, 0 znaci podrazumevani
+func validateArrivalRadius(radius float64) error {
+	if radius == 0 {
+		return nil
+	}
+	if radius < minArrivalRadius || radius > maxArrivalRadius {
+		return fmt.Errorf("%w: arrival radius must be between %.0f and %.0f meters", ErrInvalidRequest, minArrivalRadius, maxArrivalRadius)
+	}
 	return nil
 }
 
-// kalkulise i azurira distancu ture
-func (s *KeyPointService) calculateAndUpdateDistance(tourID uint) error {
-	// vrati sve keypointe za turu
+// arrivalRadiusOrDefault vraca radius iz zahteva ili podrazumevani
+func arrivalRadiusOrDefault(radius float64) float64 {
+	if radius <= 0 {
+		return DefaultArrivalRadius
+	}
+	return radius
+}
+
+// GetKeyPointSecrets vraca keypointe ture sa tajnim sadrzajem, samo autoru ture
+func (s *KeyPointService) GetKeyPointSecrets(tourID uint, authorID uint) ([]models.KeyPoint, error) {
+	tour, err := s.TourRepo.FindByID(tourID)
+	if err != nil {
+		return nil, errors.New("tour not found")
+	}
+	if tour.AuthorID != authorID {
+		return nil, errors.New("unauthorized: not tour author")
+	}
+
 	keyPoints, err := s.KeyPointRepo.FindByTourID(tourID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	secrets, err := s.KeyPointRepo.FindSecretsByTourID(tourID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range keyPoints {
+		keyPoints[i].Secret = secrets[keyPoints[i].ID]
 	}
+	return keyPoints, nil
+}
 
-	// treba mu min 2 keypointa da bi se izracunala distanca
-	if len(keyPoints) < 2 {
-		// postavi distancu na 0 ako ima manje od 2 keypointa
-		return s.TourRepo.UpdateDistance(tourID, 0)
+func secretValue(req *dto.KeyPointSecretRequest) models.KeyPointSecret {
+	return models.KeyPointSecret{Text: req.Text, Images: req.Images, AudioURL: req.AudioURL}
+}
+
+// secretFromRequest vraca tajnu za novi keypoint, nil ako je nema
+func secretFromRequest(req *dto.KeyPointSecretRequest) *models.KeyPointSecret {
+	if req == nil {
+		return nil
+	}
+	secret := secretValue(req)
+	if secret.IsEmpty() {
+		return nil
 	}
+	return &secret
+}
 
-	// racuna ukupnu distancu izmedju keypointa
-	totalDistance := 0.0
-	for i := 0; i < len(keyPoints)-1; i++ {
-		kp1 := keyPoints[i]
-		kp2 := keyPoints[i+1]
-		distance := haversineDistance(kp1.Latitude, kp1.Longitude, kp2.Latitude, kp2.Longitude)
-		totalDistance += distance
+// hideSecrets uklanja tajni sadrzaj iz keypointa pre nego sto se vrate van execution API-ja
+func hideSecrets(keyPoints []models.KeyPoint) {
+	for i := range keyPoints {
+		keyPoints[i].Secret = nil
 	}
+}
 
-	// azurira distancu ture
-	return s.TourRepo.UpdateDistance(tourID, totalDistance)
+// kalkulise i azurira distancu ture, deonice i predlozena trajanja preko routing providera
+func (s *KeyPointService) calculateAndUpdateDistance(tourID uint) error {
+	return s.Routes.Recalculate(s.TourRepo, s.KeyPointRepo, tourID)
 }
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"tour-service/internal/dto"
+	"tour-service/internal/models"
+	"tour-service/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOwnReview         = errors.New("forbidden: you cannot vote on or report your own review")
+	ErrAlreadyReported   = errors.New("you have already reported this review")
+	ErrInvalidModeration = errors.New("invalid moderation action, expected approve or remove")
+)
+
+// ReportHideThreshold je broj otvorenih prijava posle kog se recenzija sakriva dok je administrator ne pregleda
+const ReportHideThreshold = 3
+
+const maxReportDetailsLength = 1000
+
+// VoteReview upisuje ili menja glas korisnika da li je recenzija korisna
+func (s *ReviewService) VoteReview(reviewID, userID uint, req dto.ReviewVoteRequest) (*models.Review, error) {
+	var review *models.Review
+	err := s.withVisibleReview(reviewID, userID, func(reviewRepo *repository.ReviewRepository, locked *models.Review) error {
+		if err := reviewRepo.UpsertVote(&models.ReviewVote{ReviewID: reviewID, UserID: userID, Helpful: req.Helpful}); err != nil {
+			return err
+		}
+		if err := reviewRepo.RecountVotes(reviewID); err != nil {
+			return err
+		}
+		var err error
+		review, err = reviewRepo.FindByID(reviewID)
+		return err
+	})
+	return review, err
+}
+
+// RemoveVote povlaci glas korisnika sa recenzije
+func (s *ReviewService) RemoveVote(reviewID, userID uint) (*models.Review, error) {
+	var review *models.Review
+	err := s.withVisibleReview(reviewID, userID, func(reviewRepo *repository.ReviewRepository, locked *models.Review) error {
+		removed, err := reviewRepo.DeleteVote(reviewID, userID)
+		if err != nil {
+			return err
+		}
+		if removed {
+			if err := reviewRepo.RecountVotes(reviewID); err != nil {
+				return err
+			}
+		}
+		review, err = reviewRepo.FindByID(reviewID)
+		return err
+	})
+	return review, err
+}
+
+// withVisibleReview u transakciji zakljucava recenziju koju korisnik ocenjuje; sakrivene recenzije
+// se ne mogu oceniti, a ni sopstvena recenzija
+func (s *ReviewService) withVisibleReview(reviewID, userID uint, fn func(reviewRepo *repository.ReviewRepository, review *models.Review) error) error {
+	return s.tourRepo.DB.Transaction(func(tx *gorm.DB) error {
+		reviewRepo := repository.NewReviewRepository(tx)
+		review, err := reviewRepo.FindByIDForUpdate(reviewID)
+		if err != nil || !review.ModerationStatus.IsVisible() {
+			return ErrReviewNotFound
+		}
+		if review.TouristID == userID {
+			return ErrOwnReview
+		}
+		return fn(reviewRepo, review)
+	})
+}
+
+// ReportReview prijavljuje recenziju. Kada broj otvorenih prijava dostigne ReportHideThreshold, recenzija
+// se sakriva i izlazi iz proseka ture dok je administrator ne odobri ili ukloni. Odobrena recenzija se
+// vise ne sakriva automatski, ali nove prijave je vracaju u red za moderaciju.
+func (s *ReviewService) ReportReview(reviewID, reporterID uint, req dto.ReviewReportRequest) error {
+	reason := models.ReportReason(strings.TrimSpace(req.Reason))
+	switch reason {
+	case models.ReportSpam, models.ReportOffensive, models.ReportFake, models.ReportOffTopic, models.ReportOther:
+	default:
+		return fmt.Errorf("invalid report reason: %s", req.Reason)
+	}
+	details := strings.TrimSpace(req.Details)
+	if reason == models.ReportOther && details == "" {
+		return errors.New("details are required when the reason is other")
+	}
+	if len([]rune(details)) > maxReportDetailsLength {
+		return fmt.Errorf("details cannot be longer than %d characters", maxReportDetailsLength)
+	}
+
+	existing, err := s.reviewRepo.FindByID(reviewID)
+	if err != nil {
+		return ErrReviewNotFound
+	}
+
+	return s.withRatingUpdate(existing.TourID, func(reviewRepo *repository.ReviewRepository, _ *models.Tour) error {
+		review, err := reviewRepo.FindByIDForUpdate(reviewID)
+		if err != nil || review.ModerationStatus == models.ReviewRemoved {
+			return ErrReviewNotFound
+		}
+		if review.TouristID == reporterID {
+			return ErrOwnReview
+		}
+
+		previous, err := reviewRepo.FindReport(reviewID, reporterID)
+		if err != nil {
+			return err
+		}
+		if previous != nil {
+			return ErrAlreadyReported
+		}
+		report := &models.ReviewReport{ReviewID: reviewID, ReporterID: reporterID, Reason: reason, Details: details}
+		if err := reviewRepo.CreateReport(report); err != nil {
+			return err
+		}
+
+		open, err := reviewRepo.CountOpenReports(reviewID)
+		if err != nil {
+			return err
+		}
+		fields := map[string]interface{}{"report_count": open}
+		if review.ModerationStatus == models.ReviewVisible && open >= ReportHideThreshold {
+			fields["moderation_status"] = models.ReviewHidden
+		}
+		return reviewRepo.UpdateFields(reviewID, fields)
+	})
+}
+
+// GetModerationQueue vraca recenzije koje cekaju administratora, sa otvorenim prijavama
+func (s *ReviewService) GetModerationQueue(page, limit int) (*dto.PagedModerationQueueResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultReviewPageSize
+	}
+	if limit > maxReviewPageSize {
+		limit = maxReviewPageSize
+	}
+
+	reviews, total, err := s.reviewRepo.FindModerationQueue(page, limit)
+	if err != nil {
+		return nil, err
+	}
+	reviewIDs := make([]uint, len(reviews))
+	for i, review := range reviews {
+		reviewIDs[i] = review.ID
+	}
+	reports, err := s.reviewRepo.FindOpenReportsByReviewIDs(reviewIDs)
+	if err != nil {
+		return nil, err
+	}
+	reportsByReview := make(map[uint][]models.ReviewReport)
+	for _, report := range reports {
+		reportsByReview[report.ReviewID] = append(reportsByReview[report.ReviewID], report)
+	}
+
+	items := make([]dto.ModerationQueueItem, 0, len(reviews))
+	for _, review := range reviews {
+		itemReports := reportsByReview[review.ID]
+		if itemReports == nil {
+			itemReports = []models.ReviewReport{}
+		}
+		items = append(items, dto.ModerationQueueItem{Review: review, Reports: itemReports})
+	}
+	return &dto.PagedModerationQueueResponse{
+		Results:    items,
+		TotalCount: total,
+		Page:       page,
+		Limit:      limit,
+	}, nil
+}
+
+// ModerateReview odobrava ili uklanja prijavljenu recenziju i zatvara njene prijave.
+// Prosek ture se racuna ponovo, pa uklonjena recenzija vise ne ulazi u njega, a odobrena se vraca.
+func (s *ReviewService) ModerateReview(reviewID uint, req dto.ModerateReviewRequest) (*models.Review, error) {
+	var status models.ReviewModerationStatus
+	var resolution string
+	switch req.Action {
+	case "approve":
+		status, resolution = models.ReviewApproved, "approved"
+	case "remove":
+		status, resolution = models.ReviewRemoved, "removed"
+	default:
+		return nil, ErrInvalidModeration
+	}
+
+	existing, err := s.reviewRepo.FindByID(reviewID)
+	if err != nil {
+		return nil, ErrReviewNotFound
+	}
+
+	var review *models.Review
+	err = s.withRatingUpdate(existing.TourID, func(reviewRepo *repository.ReviewRepository, _ *models.Tour) error {
+		if _, err := reviewRepo.FindByIDForUpdate(reviewID); err != nil {
+			return ErrReviewNotFound
+		}
+		if err := reviewRepo.ResolveReports(reviewID, resolution, time.Now()); err != nil {
+			return err
+		}
+		err := reviewRepo.UpdateFields(reviewID, map[string]interface{}{
+			"moderation_status": status,
+			"report_count":      0,
+		})
+		if err != nil {
+			return err
+		}
+		review, err = reviewRepo.FindByID(reviewID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return review, nil
+}
@@ -1,29 +1,59 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"log"
+	"math"
+	"strings"
+	"time"
 	"tour-service/internal/dto"
 	"tour-service/internal/models"
 	"tour-service/internal/repository"
+	"userdirectory"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrReviewNotFound      = errors.New("review not found")
+	ErrReviewNotAllowed    = errors.New("forbidden: only tourists who bought and took the tour can review it")
+	ErrReviewExists        = errors.New("you have already reviewed this tour, edit your existing review instead")
+	ErrPurchaseUnavailable = errors.New("purchase verification is unavailable, try again later")
+	ErrReplyForbidden      = errors.New("forbidden: only the tour author can reply to its reviews")
 )
 
+// maxReplyLength je najduzi dozvoljen odgovor autora na recenziju
+const maxReplyLength = 2000
+
+// MinReviewProgress je udeo keypointa koji turista mora da obidje u nekom izvrsavanju da bi ocenio turu
+const MinReviewProgress = 0.35
+
+// visitDateTolerance: datum posete sa frontenda je ponoc lokalnog vremena, pa moze da bude do dan pre publishovanja u UTC
+const visitDateTolerance = 24 * time.Hour
+
 type ReviewService struct {
-	reviewRepo *repository.ReviewRepository
-	tourRepo   *repository.TourRepository
+	reviewRepo      *repository.ReviewRepository
+	tourRepo        *repository.TourRepository
+	executionRepo   *repository.TourExecutionRepository
+	purchaseChecker PurchaseChecker
+	users           *userdirectory.Client
 }
 
-func NewReviewService(reviewRepo *repository.ReviewRepository, tourRepo *repository.TourRepository) *ReviewService {
+func NewReviewService(reviewRepo *repository.ReviewRepository, tourRepo *repository.TourRepository, executionRepo *repository.TourExecutionRepository, purchaseChecker PurchaseChecker, users *userdirectory.Client) *ReviewService {
 	return &ReviewService{
-		reviewRepo: reviewRepo,
-		tourRepo:   tourRepo,
+		reviewRepo:      reviewRepo,
+		tourRepo:        tourRepo,
+		executionRepo:   executionRepo,
+		purchaseChecker: purchaseChecker,
+		users:           users,
 	}
 }
 
-// CreateReview creates a new review
+// CreateReview creates a new review. Only a tourist who bought the tour and got far enough
+// in one of its executions can review it, once per tour.
 func (s *ReviewService) CreateReview(touristID uint, req dto.CreateReviewRequest) (*models.Review, error) {
 	// Verify tour exists and is published
 	tour, err := s.tourRepo.FindByID(req.TourID)
@@ -33,14 +63,28 @@ func (s *ReviewService) CreateReview(touristID uint, req dto.CreateReviewRequest
 	if tour.Status != models.Published {
 		return nil, errors.New("can only review published tours")
 	}
+	if err := validateRating(req.Rating); err != nil {
+		return nil, err
+	}
+	if err := validateVisitDate(req.VisitDate, tour); err != nil {
+		return nil, err
+	}
 
-	// Get tourist username from stakeholders service
-	username, err := s.getUserUsername(touristID)
+	existing, err := s.reviewRepo.FindByTourAndTourist(tour.ID, touristID)
 	if err != nil {
-		// If we can't get username, use a default
-		username = fmt.Sprintf("User_%d", touristID)
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrReviewExists
+	}
+
+	if err := s.checkCanReview(touristID, tour.ID); err != nil {
+		return nil, err
 	}
 
+	// Get tourist username from the user directory, with a default if it can't be resolved
+	username := s.users.Username(context.Background(), touristID, fmt.Sprintf("User_%d", touristID))
+
 	// Convert images array to JSON string
 	imagesJSON, err := json.Marshal(req.Images)
 	if err != nil {
@@ -57,8 +101,14 @@ func (s *ReviewService) CreateReview(touristID uint, req dto.CreateReviewRequest
 		Images:          string(imagesJSON),
 	}
 
-	err = s.reviewRepo.Create(review)
+	err = s.withRatingUpdate(tour.ID, func(reviewRepo *repository.ReviewRepository, _ *models.Tour) error {
+		return reviewRepo.Create(review)
+	})
 	if err != nil {
+		// istovremeno poslata druga recenzija je vec upisana
+		if isDuplicateKey(err) {
+			return nil, ErrReviewExists
+		}
 		return nil, err
 	}
 
@@ -70,9 +120,49 @@ func (s *ReviewService) GetReviewByID(id uint) (*models.Review, error) {
 	return s.reviewRepo.FindByID(id)
 }
 
-// GetReviewsByTourID retrieves all reviews for a tour
-func (s *ReviewService) GetReviewsByTourID(tourID uint) ([]models.Review, error) {
-	return s.reviewRepo.FindByTourID(tourID)
+const (
+	defaultReviewPageSize = 10
+	maxReviewPageSize     = 50
+)
+
+// ratingTrendPeriods su periodi (u danima) za koje se racuna trend ocena
+var ratingTrendPeriods = []int{30, 90}
+
+// GetReviewsByTourID retrieves one page of a tour's reviews
+func (s *ReviewService) GetReviewsByTourID(tourID uint, query dto.ReviewListQuery) (*dto.PagedReviewsResponse, error) {
+	for _, rating := range query.Ratings {
+		if err := validateRating(rating); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+		}
+	}
+	if query.Sort != "" {
+		switch query.Sort {
+		case "newest", "highest", "lowest", "most_helpful":
+		default:
+			return nil, fmt.Errorf("%w: invalid sort: %s", ErrInvalidQuery, query.Sort)
+		}
+	}
+
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 {
+		query.Limit = defaultReviewPageSize
+	}
+	if query.Limit > maxReviewPageSize {
+		query.Limit = maxReviewPageSize
+	}
+
+	reviews, total, err := s.reviewRepo.FindPageByTourID(tourID, query)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.PagedReviewsResponse{
+		Results:    reviews,
+		TotalCount: total,
+		Page:       query.Page,
+		Limit:      query.Limit,
+	}, nil
 }
 
 // GetReviewsByTouristID retrieves all reviews by a tourist
@@ -82,59 +172,182 @@ func (s *ReviewService) GetReviewsByTouristID(touristID uint) ([]models.Review,
 
 // UpdateReview updates an existing review
 func (s *ReviewService) UpdateReview(reviewID, touristID uint, req dto.UpdateReviewRequest) (*models.Review, error) {
-	review, err := s.reviewRepo.FindByID(reviewID)
+	existing, err := s.reviewRepo.FindByID(reviewID)
 	if err != nil {
-		return nil, errors.New("review not found")
+		return nil, ErrReviewNotFound
 	}
 
-	// Verify ownership
-	if review.TouristID != touristID {
-		return nil, errors.New("unauthorized to update this review")
-	}
+	var review *models.Review
+	err = s.withRatingUpdate(existing.TourID, func(reviewRepo *repository.ReviewRepository, tour *models.Tour) error {
+		review, err = reviewRepo.FindByIDForUpdate(reviewID)
+		if err != nil {
+			return ErrReviewNotFound
+		}
 
-	// Update fields if provided
-	if req.Rating > 0 {
-		review.Rating = req.Rating
-	}
-	if req.Comment != "" {
-		review.Comment = req.Comment
+		// Verify ownership
+		if review.TouristID != touristID {
+			return errors.New("unauthorized to update this review")
+		}
+
+		// Update fields if provided
+		if req.Rating != 0 {
+			if err := validateRating(req.Rating); err != nil {
+				return err
+			}
+			review.Rating = req.Rating
+		}
+		if req.Comment != "" {
+			review.Comment = req.Comment
+		}
+		if !req.VisitDate.IsZero() {
+			if err := validateVisitDate(req.VisitDate, tour); err != nil {
+				return err
+			}
+			review.VisitDate = req.VisitDate
+		}
+		if req.Images != nil {
+			imagesJSON, err := json.Marshal(req.Images)
+			if err != nil {
+				return err
+			}
+			review.Images = string(imagesJSON)
+		}
+
+		return reviewRepo.Update(review)
+	})
+	if err != nil {
+		return nil, err
 	}
-	if !req.VisitDate.IsZero() {
-		review.VisitDate = req.VisitDate
+
+	return review, nil
+}
+
+// DeleteReview deletes a review
+func (s *ReviewService) DeleteReview(reviewID, touristID uint) error {
+	existing, err := s.reviewRepo.FindByID(reviewID)
+	if err != nil {
+		return ErrReviewNotFound
 	}
-	if req.Images != nil {
-		imagesJSON, err := json.Marshal(req.Images)
+
+	return s.withRatingUpdate(existing.TourID, func(reviewRepo *repository.ReviewRepository, tour *models.Tour) error {
+		review, err := reviewRepo.FindByIDForUpdate(reviewID)
 		if err != nil {
-			return nil, err
+			return ErrReviewNotFound
 		}
-		review.Images = string(imagesJSON)
+
+		// Verify ownership
+		if review.TouristID != touristID {
+			return errors.New("unauthorized to delete this review")
+		}
+
+		return reviewRepo.Delete(reviewID)
+	})
+}
+
+// ReplyToReview dodaje ili menja javni odgovor autora ture na recenziju. Recenzija ima najvise jedan odgovor,
+// a turista dobija oznaku da je odgovor nov dok ga ne pogleda.
+func (s *ReviewService) ReplyToReview(reviewID, authorID uint, req dto.ReviewReplyRequest) (*models.Review, error) {
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		return nil, errors.New("reply text is required")
+	}
+	if len([]rune(text)) > maxReplyLength {
+		return nil, fmt.Errorf("reply cannot be longer than %d characters", maxReplyLength)
 	}
 
-	err = s.reviewRepo.Update(review)
+	review, err := s.reviewForAuthor(reviewID, authorID)
 	if err != nil {
 		return nil, err
 	}
 
-	return review, nil
+	now := time.Now()
+	fields := map[string]interface{}{
+		"author_reply":            text,
+		"author_reply_updated_at": now,
+		"reply_unread":            true,
+	}
+	if review.AuthorReplyAt == nil {
+		fields["author_reply_at"] = now
+	}
+	if err := s.reviewRepo.UpdateFields(review.ID, fields); err != nil {
+		return nil, err
+	}
+	return s.reviewRepo.FindByID(review.ID)
 }
 
-// DeleteReview deletes a review
-func (s *ReviewService) DeleteReview(reviewID, touristID uint) error {
-	review, err := s.reviewRepo.FindByID(reviewID)
+// DeleteReply uklanja odgovor autora ture sa recenzije
+func (s *ReviewService) DeleteReply(reviewID, authorID uint) error {
+	review, err := s.reviewForAuthor(reviewID, authorID)
 	if err != nil {
-		return errors.New("review not found")
+		return err
+	}
+	if review.AuthorReplyAt == nil {
+		return fmt.Errorf("%w: review has no reply", ErrReviewNotFound)
 	}
 
-	// Verify ownership
+	return s.reviewRepo.UpdateFields(review.ID, map[string]interface{}{
+		"author_reply":            "",
+		"author_reply_at":         nil,
+		"author_reply_updated_at": nil,
+		"reply_unread":            false,
+	})
+}
+
+// MarkReplyRead oznacava da je turista video odgovor autora na svoju recenziju
+func (s *ReviewService) MarkReplyRead(reviewID, touristID uint) error {
+	review, err := s.reviewRepo.FindByID(reviewID)
+	if err != nil {
+		return ErrReviewNotFound
+	}
 	if review.TouristID != touristID {
-		return errors.New("unauthorized to delete this review")
+		return errors.New("unauthorized to update this review")
 	}
+	if !review.ReplyUnread {
+		return nil
+	}
+	return s.reviewRepo.UpdateFields(review.ID, map[string]interface{}{"reply_unread": false})
+}
+
+// CountUnreadReplies vraca broj recenzija turiste sa odgovorom koji jos nije video
+func (s *ReviewService) CountUnreadReplies(touristID uint) (int64, error) {
+	return s.reviewRepo.CountUnreadReplies(touristID)
+}
+
+// reviewForAuthor vraca recenziju ako je authorID autor ocenjene ture
+func (s *ReviewService) reviewForAuthor(reviewID, authorID uint) (*models.Review, error) {
+	review, err := s.reviewRepo.FindByID(reviewID)
+	if err != nil {
+		return nil, ErrReviewNotFound
+	}
+	tour, err := s.tourRepo.FindByIDIncludingDeleted(review.TourID)
+	if err != nil {
+		return nil, errors.New("tour not found")
+	}
+	if tour.AuthorID != authorID {
+		return nil, ErrReplyForbidden
+	}
+	return review, nil
+}
 
-	return s.reviewRepo.Delete(reviewID)
+// withRatingUpdate menja recenzije ture u transakciji i na kraju ponovo racuna AverageRating i ReviewCount ture.
+// Red ture je zakljucan do kraja transakcije, pa istovremene izmene recenzija iste ture ne mogu da upisu zastareo prosek.
+func (s *ReviewService) withRatingUpdate(tourID uint, fn func(reviewRepo *repository.ReviewRepository, tour *models.Tour) error) error {
+	return s.tourRepo.DB.Transaction(func(tx *gorm.DB) error {
+		tourRepo := repository.NewTourRepository(tx)
+		tour, err := tourRepo.FindByIDForUpdate(tourID)
+		if err != nil {
+			return errors.New("tour not found")
+		}
+		if err := fn(repository.NewReviewRepository(tx), tour); err != nil {
+			return err
+		}
+		return tourRepo.RecalculateRating(tourID)
+	})
 }
 
-// GetTourRatingStats returns rating statistics for a tour
-func (s *ReviewService) GetTourRatingStats(tourID uint) (map[string]interface{}, error) {
+// GetTourRatingStats returns rating statistics for a tour: average, count, count per rating
+// and the trend of the last 30 and 90 days compared to the ratings before them
+func (s *ReviewService) GetTourRatingStats(tourID uint) (*dto.ReviewStatsResponse, error) {
 	avgRating, err := s.reviewRepo.GetAverageRating(tourID)
 	if err != nil {
 		return nil, err
@@ -145,43 +358,92 @@ func (s *ReviewService) GetTourRatingStats(tourID uint) (map[string]interface{},
 		return nil, err
 	}
 
-	return map[string]interface{}{
-		"averageRating": avgRating,
-		"reviewCount":   reviewCount,
+	counts, err := s.reviewRepo.GetRatingHistogram(tourID)
+	if err != nil {
+		return nil, err
+	}
+	histogram := make(map[int]int64, 5)
+	for rating := 1; rating <= 5; rating++ {
+		histogram[rating] = counts[rating]
+	}
+
+	now := time.Now()
+	trend := make([]dto.RatingTrend, 0, len(ratingTrendPeriods))
+	for _, days := range ratingTrendPeriods {
+		since := now.AddDate(0, 0, -days)
+		count, average, err := s.reviewRepo.GetRatingSummary(tourID, &since, nil)
+		if err != nil {
+			return nil, err
+		}
+		beforeCount, beforeAverage, err := s.reviewRepo.GetRatingSummary(tourID, nil, &since)
+		if err != nil {
+			return nil, err
+		}
+
+		period := dto.RatingTrend{Days: days, ReviewCount: count, AverageRating: average}
+		if count > 0 && beforeCount > 0 {
+			change := average - beforeAverage
+			period.Change = &change
+		}
+		trend = append(trend, period)
+	}
+
+	return &dto.ReviewStatsResponse{
+		AverageRating: avgRating,
+		ReviewCount:   reviewCount,
+		Histogram:     histogram,
+		Trend:         trend,
 	}, nil
 }
 
-// getUserUsername fetches username from stakeholders service
-func (s *ReviewService) getUserUsername(userID uint) (string, error) {
-	url := fmt.Sprintf("http://stakeholders-service:8080/api/v1/users/batch?ids=%d", userID)
-
-	resp, err := http.Get(url)
+// checkCanReview proverava da je turista kupio turu (gRPC VerifyPurchase) i da je u nekom
+// izvrsavanju obisao bar MinReviewProgress keypointa
+func (s *ReviewService) checkCanReview(touristID, tourID uint) error {
+	hasPurchased, err := s.purchaseChecker.HasPurchasedTour(touristID, tourID)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch user: %w", err)
+		log.Printf("Purchase verification failed for tourist %d, tour %d: %v", touristID, tourID, err)
+		return ErrPurchaseUnavailable
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("user not found: status %d", resp.StatusCode)
+	if !hasPurchased {
+		return fmt.Errorf("%w: tour was not purchased", ErrReviewNotAllowed)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	executions, err := s.executionRepo.GetExecutionsByTouristAndTour(touristID, tourID)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return err
 	}
-
-	var users []struct {
-		ID       uint   `json:"id"`
-		Username string `json:"username"`
+	best := 0.0
+	for i := range executions {
+		progress, err := executionProgress(s.executionRepo, &executions[i])
+		if err != nil {
+			return err
+		}
+		best = math.Max(best, progress)
 	}
-
-	if err := json.Unmarshal(body, &users); err != nil {
-		return "", fmt.Errorf("failed to parse users: %w", err)
+	if best < MinReviewProgress {
+		return fmt.Errorf("%w: at least %.0f%% of the key points must be visited, best progress is %.0f%%",
+			ErrReviewNotAllowed, MinReviewProgress*100, best*100)
 	}
+	return nil
+}
 
-	if len(users) == 0 {
-		return "", fmt.Errorf("user not found")
+func validateRating(rating int) error {
+	if rating < 1 || rating > 5 {
+		return errors.New("rating must be between 1 and 5")
 	}
+	return nil
+}
 
-	return users[0].Username, nil
+// validateVisitDate odbija datum posete u buducnosti ili pre nego sto je tura publishovana
+func validateVisitDate(visitDate time.Time, tour *models.Tour) error {
+	if visitDate.IsZero() {
+		return errors.New("visit date is required")
+	}
+	if visitDate.After(time.Now()) {
+		return errors.New("visit date cannot be in the future")
+	}
+	if tour.PublishedAt != nil && visitDate.Before(tour.PublishedAt.Add(-visitDateTolerance)) {
+		return errors.New("visit date cannot be before the tour was published")
+	}
+	return nil
 }
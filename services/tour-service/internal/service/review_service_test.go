@@ -0,0 +1,20 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"tour-service/internal/dto"
+)
+
+func TestGetReviewsByTourIDRejectsInvalidQuery(t *testing.T) {
+	s := &ReviewService{}
+	queries := map[string]dto.ReviewListQuery{
+		"rating out of range": {Ratings: []int{6}},
+		"unknown sort":        {Sort: "oldest"},
+	}
+	for name, query := range queries {
+		if _, err := s.GetReviewsByTourID(1, query); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("%s: expected ErrInvalidQuery, got %v", name, err)
+		}
+	}
+}
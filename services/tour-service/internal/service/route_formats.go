@@ -0,0 +1,255 @@
+package service
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"tour-service/internal/models"
+)
+
+// RouteFormat je format u kome se ruta ture izvozi ili uvozi
+type RouteFormat string
+
+const (
+	FormatGPX     RouteFormat = "gpx"
+	FormatKML     RouteFormat = "kml"
+	FormatGeoJSON RouteFormat = "geojson"
+)
+
+// ContentType vraca MIME tip formata
+func (f RouteFormat) ContentType() string {
+	switch f {
+	case FormatGPX:
+		return "application/gpx+xml"
+	case FormatKML:
+		return "application/vnd.google-earth.kml+xml"
+	default:
+		return "application/geo+json"
+	}
+}
+
+// ParseRouteFormat prepoznaje format iz ?format= vrednosti
+func ParseRouteFormat(value string) (RouteFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "gpx":
+		return FormatGPX, nil
+	case "kml":
+		return FormatKML, nil
+	case "geojson", "json":
+		return FormatGeoJSON, nil
+	}
+	return "", fmt.Errorf("unsupported format: %s", value)
+}
+
+// RouteFormatFromAccept bira format iz Accept headera, a ako nijedan ne odgovara vraca GeoJSON
+func RouteFormatFromAccept(accept string) RouteFormat {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		switch strings.ToLower(mediaType) {
+		case "application/gpx+xml":
+			return FormatGPX
+		case "application/vnd.google-earth.kml+xml":
+			return FormatKML
+		case "application/geo+json", "application/json":
+			return FormatGeoJSON
+		}
+	}
+	return FormatGeoJSON
+}
+
+// --- GPX 1.1 ---
+
+type gpxDocument struct {
+	XMLName   xml.Name     `xml:"gpx"`
+	Version   string       `xml:"version,attr"`
+	Creator   string       `xml:"creator,attr"`
+	XMLNS     string       `xml:"xmlns,attr,omitempty"`
+	Metadata  *gpxMetadata `xml:"metadata,omitempty"`
+	Waypoints []gpxPoint   `xml:"wpt"`
+	Routes    []gpxRoute   `xml:"rte"`
+	Tracks    []gpxTrack   `xml:"trk"`
+}
+
+type gpxMetadata struct {
+	Name        string `xml:"name,omitempty"`
+	Description string `xml:"desc,omitempty"`
+}
+
+type gpxPoint struct {
+	Latitude    string `xml:"lat,attr"`
+	Longitude   string `xml:"lon,attr"`
+	Time        string `xml:"time,omitempty"`
+	Name        string `xml:"name,omitempty"`
+	Description string `xml:"desc,omitempty"`
+}
+
+type gpxRoute struct {
+	Name   string     `xml:"name,omitempty"`
+	Points []gpxPoint `xml:"rtept"`
+}
+
+type gpxTrack struct {
+	Name     string            `xml:"name,omitempty"`
+	Segments []gpxTrackSegment `xml:"trkseg"`
+}
+
+type gpxTrackSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+// --- KML 2.2 ---
+
+type kmlDocument struct {
+	XMLName  xml.Name  `xml:"kml"`
+	XMLNS    string    `xml:"xmlns,attr"`
+	Document kmlFolder `xml:"Document"`
+}
+
+type kmlFolder struct {
+	Name        string         `xml:"name,omitempty"`
+	Description string         `xml:"description,omitempty"`
+	Placemarks  []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name        string       `xml:"name,omitempty"`
+	Description string       `xml:"description,omitempty"`
+	Point       *kmlGeometry `xml:"Point,omitempty"`
+	LineString  *kmlGeometry `xml:"LineString,omitempty"`
+}
+
+type kmlGeometry struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// --- GeoJSON (RFC 7946) ---
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   *geoJSONGeometry       `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+func formatCoordinate(value float64) string {
+	return fmt.Sprintf("%.7f", value)
+}
+
+// RenderRoute renderuje turu i njene keypointe (po redosledu) u trazeni format
+func RenderRoute(tour *models.Tour, format RouteFormat) ([]byte, error) {
+	switch format {
+	case FormatGPX:
+		return renderGPX(tour)
+	case FormatKML:
+		return renderKML(tour)
+	case FormatGeoJSON:
+		return renderGeoJSON(tour)
+	}
+	return nil, errors.New("unsupported format")
+}
+
+func renderGPX(tour *models.Tour) ([]byte, error) {
+	doc := gpxDocument{
+		Version:  "1.1",
+		Creator:  "tour-service",
+		XMLNS:    "http://www.topografix.com/GPX/1/1",
+		Metadata: &gpxMetadata{Name: tour.Name, Description: tour.Description},
+	}
+
+	route := gpxRoute{Name: tour.Name}
+	for _, kp := range tour.KeyPoints {
+		point := gpxPoint{
+			Latitude:    formatCoordinate(kp.Latitude),
+			Longitude:   formatCoordinate(kp.Longitude),
+			Name:        kp.Name,
+			Description: kp.Description,
+		}
+		doc.Waypoints = append(doc.Waypoints, point)
+		route.Points = append(route.Points, gpxPoint{Latitude: point.Latitude, Longitude: point.Longitude, Name: kp.Name})
+	}
+	if len(route.Points) > 0 {
+		doc.Routes = []gpxRoute{route}
+	}
+
+	return marshalXML(doc)
+}
+
+func renderKML(tour *models.Tour) ([]byte, error) {
+	doc := kmlDocument{
+		XMLNS:    "http://www.opengis.net/kml/2.2",
+		Document: kmlFolder{Name: tour.Name, Description: tour.Description},
+	}
+
+	var line []string
+	for _, kp := range tour.KeyPoints {
+		coordinates := formatCoordinate(kp.Longitude) + "," + formatCoordinate(kp.Latitude)
+		line = append(line, coordinates)
+		doc.Document.Placemarks = append(doc.Document.Placemarks, kmlPlacemark{
+			Name:        kp.Name,
+			Description: kp.Description,
+			Point:       &kmlGeometry{Coordinates: coordinates},
+		})
+	}
+	if len(line) >= 2 {
+		doc.Document.Placemarks = append(doc.Document.Placemarks, kmlPlacemark{
+			Name:       tour.Name,
+			LineString: &kmlGeometry{Coordinates: strings.Join(line, " ")},
+		})
+	}
+
+	return marshalXML(doc)
+}
+
+func renderGeoJSON(tour *models.Tour) ([]byte, error) {
+	collection := geoJSONFeatureCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+
+	var line [][2]float64
+	for _, kp := range tour.KeyPoints {
+		position := [2]float64{kp.Longitude, kp.Latitude}
+		line = append(line, position)
+		coordinates, _ := json.Marshal(position)
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type:     "Feature",
+			Geometry: &geoJSONGeometry{Type: "Point", Coordinates: coordinates},
+			Properties: map[string]interface{}{
+				"id":          kp.ID,
+				"name":        kp.Name,
+				"description": kp.Description,
+				"order":       kp.Order,
+			},
+		})
+	}
+	if len(line) >= 2 {
+		coordinates, _ := json.Marshal(line)
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type:     "Feature",
+			Geometry: &geoJSONGeometry{Type: "LineString", Coordinates: coordinates},
+			Properties: map[string]interface{}{
+				"tourId":   tour.ID,
+				"name":     tour.Name,
+				"distance": tour.Distance,
+			},
+		})
+	}
+
+	return json.Marshal(collection)
+}
+
+func marshalXML(doc interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
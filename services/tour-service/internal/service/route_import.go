@@ -0,0 +1,348 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"tour-service/internal/interfaces"
+)
+
+const (
+	DefaultImportKeyPoints = 20
+	MaxImportKeyPoints     = 200
+)
+
+// ImportError sadrzi sve elemente fajla koji nisu mogli da se procitaju
+type ImportError struct {
+	Problems []string `json:"errors"`
+}
+
+func (e *ImportError) Error() string {
+	return "invalid route file: " + strings.Join(e.Problems, "; ")
+}
+
+func (e *ImportError) add(format string, args ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+// routePoint je jedna tacka procitana iz uvezenog fajla
+type routePoint struct {
+	Name        string
+	Description string
+	Latitude    float64
+	Longitude   float64
+}
+
+// parsedRoute je ruta procitana iz GPX ili GeoJSON fajla:
+// Waypoints su imenovane tacke, Path je cela putanja (track, ruta ili linija)
+type parsedRoute struct {
+	Name        string
+	Description string
+	Waypoints   []routePoint
+	Path        []routePoint
+}
+
+// DetectRouteFormat prepoznaje GPX ili GeoJSON po ekstenziji fajla, a zatim po sadrzaju
+func DetectRouteFormat(filename string, data []byte) (RouteFormat, error) {
+	lower := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(lower, ".gpx"):
+		return FormatGPX, nil
+	case strings.HasSuffix(lower, ".geojson"), strings.HasSuffix(lower, ".json"):
+		return FormatGeoJSON, nil
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 {
+		switch trimmed[0] {
+		case '<':
+			return FormatGPX, nil
+		case '{':
+			return FormatGeoJSON, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported route file, expected GPX or GeoJSON")
+}
+
+// parseRoute cita GPX ili GeoJSON sadrzaj
+func parseRoute(data []byte, format RouteFormat) (*parsedRoute, error) {
+	switch format {
+	case FormatGPX:
+		return parseGPX(data)
+	case FormatGeoJSON:
+		return parseGeoJSON(data)
+	}
+	return nil, fmt.Errorf("unsupported import format: %s", format)
+}
+
+func parseGPX(data []byte) (*parsedRoute, error) {
+	var doc gpxDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, &ImportError{Problems: []string{"gpx: " + err.Error()}}
+	}
+
+	route := &parsedRoute{}
+	if doc.Metadata != nil {
+		route.Name = doc.Metadata.Name
+		route.Description = doc.Metadata.Description
+	}
+
+	problems := &ImportError{}
+	for i, wpt := range doc.Waypoints {
+		if point, ok := parseGPXPoint(wpt, fmt.Sprintf("wpt[%d]", i+1), problems); ok {
+			route.Waypoints = append(route.Waypoints, point)
+		}
+	}
+
+	// putanja: track tacke imaju prednost nad rutom
+	for t, trk := range doc.Tracks {
+		if route.Name == "" {
+			route.Name = trk.Name
+		}
+		for sIdx, seg := range trk.Segments {
+			for p, pt := range seg.Points {
+				element := fmt.Sprintf("trk[%d]/trkseg[%d]/trkpt[%d]", t+1, sIdx+1, p+1)
+				if point, ok := parseGPXPoint(pt, element, problems); ok {
+					route.Path = append(route.Path, point)
+				}
+			}
+		}
+	}
+	if len(route.Path) == 0 {
+		for r, rte := range doc.Routes {
+			if route.Name == "" {
+				route.Name = rte.Name
+			}
+			for p, pt := range rte.Points {
+				if point, ok := parseGPXPoint(pt, fmt.Sprintf("rte[%d]/rtept[%d]", r+1, p+1), problems); ok {
+					route.Path = append(route.Path, point)
+				}
+			}
+		}
+	}
+
+	if len(problems.Problems) > 0 {
+		return nil, problems
+	}
+	return route, nil
+}
+
+func parseGPXPoint(pt gpxPoint, element string, problems *ImportError) (routePoint, bool) {
+	lat, latErr := strconv.ParseFloat(strings.TrimSpace(pt.Latitude), 64)
+	lon, lonErr := strconv.ParseFloat(strings.TrimSpace(pt.Longitude), 64)
+	if latErr != nil || lat < -90 || lat > 90 {
+		problems.add("%s: invalid lat %q", element, pt.Latitude)
+		return routePoint{}, false
+	}
+	if lonErr != nil || lon < -180 || lon > 180 {
+		problems.add("%s: invalid lon %q", element, pt.Longitude)
+		return routePoint{}, false
+	}
+	return routePoint{Name: pt.Name, Description: pt.Description, Latitude: lat, Longitude: lon}, true
+}
+
+// geoJSONObject pokriva FeatureCollection, Feature i samu geometriju
+type geoJSONObject struct {
+	Type        string                 `json:"type"`
+	Features    []geoJSONObject        `json:"features"`
+	Geometry    *geoJSONObject         `json:"geometry"`
+	Properties  map[string]interface{} `json:"properties"`
+	Coordinates json.RawMessage        `json:"coordinates"`
+}
+
+func parseGeoJSON(data []byte) (*parsedRoute, error) {
+	var root geoJSONObject
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, &ImportError{Problems: []string{"geojson: " + err.Error()}}
+	}
+
+	route := &parsedRoute{}
+	problems := &ImportError{}
+
+	switch root.Type {
+	case "FeatureCollection":
+		for i, feature := range root.Features {
+			readGeoJSONFeature(feature, fmt.Sprintf("features[%d]", i), route, problems)
+		}
+	case "Feature":
+		readGeoJSONFeature(root, "feature", route, problems)
+	default:
+		readGeoJSONGeometry(root, "geometry", "", "", route, problems)
+	}
+
+	if len(problems.Problems) > 0 {
+		return nil, problems
+	}
+	return route, nil
+}
+
+func readGeoJSONFeature(feature geoJSONObject, element string, route *parsedRoute, problems *ImportError) {
+	if feature.Type != "Feature" {
+		problems.add("%s: expected type Feature, got %q", element, feature.Type)
+		return
+	}
+	if feature.Geometry == nil {
+		problems.add("%s: missing geometry", element)
+		return
+	}
+	name := stringProperty(feature.Properties, "name")
+	description := stringProperty(feature.Properties, "description")
+	readGeoJSONGeometry(*feature.Geometry, element+".geometry", name, description, route, problems)
+}
+
+func readGeoJSONGeometry(geometry geoJSONObject, element, name, description string, route *parsedRoute, problems *ImportError) {
+	switch geometry.Type {
+	case "Point":
+		var position []float64
+		if err := json.Unmarshal(geometry.Coordinates, &position); err != nil {
+			problems.add("%s: invalid Point coordinates", element)
+			return
+		}
+		if point, ok := geoJSONPosition(position, element, problems); ok {
+			point.Name, point.Description = name, description
+			route.Waypoints = append(route.Waypoints, point)
+		}
+	case "MultiPoint":
+		var positions [][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &positions); err != nil {
+			problems.add("%s: invalid MultiPoint coordinates", element)
+			return
+		}
+		for i, position := range positions {
+			if point, ok := geoJSONPosition(position, fmt.Sprintf("%s.coordinates[%d]", element, i), problems); ok {
+				route.Waypoints = append(route.Waypoints, point)
+			}
+		}
+	case "LineString":
+		var positions [][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &positions); err != nil {
+			problems.add("%s: invalid LineString coordinates", element)
+			return
+		}
+		if route.Name == "" {
+			route.Name, route.Description = name, description
+		}
+		for i, position := range positions {
+			if point, ok := geoJSONPosition(position, fmt.Sprintf("%s.coordinates[%d]", element, i), problems); ok {
+				route.Path = append(route.Path, point)
+			}
+		}
+	case "MultiLineString":
+		var lines [][][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &lines); err != nil {
+			problems.add("%s: invalid MultiLineString coordinates", element)
+			return
+		}
+		if route.Name == "" {
+			route.Name, route.Description = name, description
+		}
+		for l, line := range lines {
+			for i, position := range line {
+				if point, ok := geoJSONPosition(position, fmt.Sprintf("%s.coordinates[%d][%d]", element, l, i), problems); ok {
+					route.Path = append(route.Path, point)
+				}
+			}
+		}
+	default:
+		problems.add("%s: unsupported geometry type %q", element, geometry.Type)
+	}
+}
+
+// geoJSONPosition cita [lon, lat] poziciju
+func geoJSONPosition(position []float64, element string, problems *ImportError) (routePoint, bool) {
+	if len(position) < 2 {
+		problems.add("%s: position must have longitude and latitude", element)
+		return routePoint{}, false
+	}
+	lon, lat := position[0], position[1]
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		problems.add("%s: coordinates out of range [%v, %v]", element, lon, lat)
+		return routePoint{}, false
+	}
+	return routePoint{Latitude: lat, Longitude: lon}, true
+}
+
+func stringProperty(properties map[string]interface{}, key string) string {
+	if value, ok := properties[key].(string); ok {
+		return value
+	}
+	return ""
+}
+
+// pathDistance racuna duzinu cele putanje u kilometrima
+func pathDistance(points []routePoint) float64 {
+	total := 0.0
+	for i := 0; i < len(points)-1; i++ {
+		total += haversineDistance(points[i].Latitude, points[i].Longitude, points[i+1].Latitude, points[i+1].Longitude)
+	}
+	return total
+}
+
+// trackRoute pravi pesacku rutu iz uvezenog tracka: ukupna distanca je duzina tracka, a deonica izmedju
+// dva keypointa je duzina tracka izmedju njima najblizih tacaka (trazi se unapred, po redosledu keypointa).
+// Trajanje nije poznato, pa se predlozena trajanja racunaju prosecnim brzinama.
+func trackRoute(track []routePoint, keyPoints []routePoint) *interfaces.RouteEstimate {
+	estimate := &interfaces.RouteEstimate{DistanceKm: pathDistance(track)}
+	previous := -1
+	for _, kp := range keyPoints {
+		nearest, best := previous, math.Inf(1)
+		for i := max(previous, 0); i < len(track); i++ {
+			if d := haversineDistance(kp.Latitude, kp.Longitude, track[i].Latitude, track[i].Longitude); d < best {
+				nearest, best = i, d
+			}
+		}
+		if previous >= 0 {
+			estimate.LegDistancesKm = append(estimate.LegDistancesKm, pathDistance(track[previous:nearest+1]))
+		}
+		previous = nearest
+	}
+	return estimate
+}
+
+// downsamplePath bira najvise maxPoints tacaka ravnomerno rasporedjenih po predjenoj distanci,
+// uvek zadrzavajuci prvu i poslednju tacku
+func downsamplePath(points []routePoint, maxPoints int) []routePoint {
+	if len(points) <= maxPoints || maxPoints < 2 {
+		if maxPoints == 1 && len(points) > 0 {
+			return points[:1]
+		}
+		return points
+	}
+
+	cumulative := make([]float64, len(points))
+	for i := 1; i < len(points); i++ {
+		cumulative[i] = cumulative[i-1] + haversineDistance(points[i-1].Latitude, points[i-1].Longitude, points[i].Latitude, points[i].Longitude)
+	}
+	total := cumulative[len(cumulative)-1]
+
+	result := make([]routePoint, 0, maxPoints)
+	last := -1
+	index := 0
+	for k := 0; k < maxPoints; k++ {
+		target := total * float64(k) / float64(maxPoints-1)
+		for index < len(points)-1 && cumulative[index+1] <= target {
+			index++
+		}
+		chosen := index
+		if index < len(points)-1 && cumulative[index+1]-target < target-cumulative[index] {
+			chosen = index + 1
+		}
+		if k == maxPoints-1 {
+			chosen = len(points) - 1
+		}
+		if chosen <= last {
+			// vise ciljeva je palo na istu tacku (npr. track koji stoji u mestu) - uzmi sledecu
+			chosen = last + 1
+			if chosen >= len(points) {
+				break
+			}
+		}
+		result = append(result, points[chosen])
+		last = chosen
+	}
+	return result
+}
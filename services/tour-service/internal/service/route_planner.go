@@ -0,0 +1,151 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"tour-service/internal/interfaces"
+	"tour-service/internal/models"
+	"tour-service/internal/repository"
+)
+
+// prosecne brzine (km/h) za procenu trajanja po vazdusnoj liniji
+var averageSpeedsKmh = map[models.TransportType]float64{
+	models.Walking: 5,
+	models.Bicycle: 15,
+	models.Car:     50,
+}
+
+// suggestedTransportTypes su tipovi prevoza za koje se predlazu trajanja
+var suggestedTransportTypes = []models.TransportType{models.Walking, models.Bicycle, models.Car}
+
+// HaversineRoutingProvider racuna rutu kao zbir vazdusnih distanci izmedju tacaka
+type HaversineRoutingProvider struct{}
+
+func NewHaversineRoutingProvider() interfaces.RoutingProvider {
+	return &HaversineRoutingProvider{}
+}
+
+func (p *HaversineRoutingProvider) Route(transport models.TransportType, points []interfaces.Coordinate) (*interfaces.RouteEstimate, error) {
+	speed, ok := averageSpeedsKmh[transport]
+	if !ok {
+		return nil, fmt.Errorf("unsupported transport type: %s", transport)
+	}
+
+	estimate := &interfaces.RouteEstimate{Estimated: true}
+	for i := 0; i < len(points)-1; i++ {
+		leg := haversineDistance(points[i].Latitude, points[i].Longitude, points[i+1].Latitude, points[i+1].Longitude)
+		estimate.LegDistancesKm = append(estimate.LegDistancesKm, leg)
+		estimate.DistanceKm += leg
+	}
+	estimate.DurationMin = int(math.Ceil(estimate.DistanceKm / speed * 60))
+	return estimate, nil
+}
+
+// RoutePlanner preracunava distancu ture, distance po deonicama i predlozena trajanja kada se keypointi promene.
+// Ako glavni provider ne odgovori, koristi se Fallback (vazdusna linija) i tura se oznacava kao procenjena.
+type RoutePlanner struct {
+	Provider interfaces.RoutingProvider
+	Fallback interfaces.RoutingProvider
+}
+
+func NewRoutePlanner(provider interfaces.RoutingProvider) *RoutePlanner {
+	return &RoutePlanner{
+		Provider: provider,
+		Fallback: NewHaversineRoutingProvider(),
+	}
+}
+
+// route rutira pesacku putanju kroz tacke jednim zahtevom; ako provider ne odgovori koristi se Fallback,
+// a rezultat je oznacen kao procena
+func (p *RoutePlanner) route(points []interfaces.Coordinate) (*interfaces.RouteEstimate, error) {
+	estimate, err := p.Provider.Route(models.Walking, points)
+	if err == nil {
+		return estimate, nil
+	}
+	log.Printf("WARNING: routing provider failed: %v - storing straight-line estimate instead", err)
+	estimate, err = p.Fallback.Route(models.Walking, points)
+	if err != nil {
+		return nil, err
+	}
+	estimate.Estimated = true
+	return estimate, nil
+}
+
+// suggestedDuration racuna trajanje za tip prevoza: pesacko vraca provider, ostala se
+// skaliraju prosecnim brzinama po istoj ruti, pa nema dodatnih zahteva po tipu prevoza
+func suggestedDuration(walking *interfaces.RouteEstimate, transport models.TransportType) int {
+	if transport == models.Walking && walking.DurationMin > 0 {
+		return walking.DurationMin
+	}
+	return int(math.Ceil(walking.DistanceKm / averageSpeedsKmh[transport] * 60))
+}
+
+// Recalculate racuna rutu kroz keypointe ture (po redosledu) i cuva distancu ture,
+// distancu deonice na svakom keypointu i predlozena trajanja za svaki tip prevoza
+func (p *RoutePlanner) Recalculate(tourRepo *repository.TourRepository, keyPointRepo *repository.KeyPointRepository, tourID uint) error {
+	keyPoints, err := keyPointRepo.FindByTourID(tourID)
+	if err != nil {
+		return err
+	}
+
+	if len(keyPoints) < 2 {
+		for _, kp := range keyPoints {
+			if err := keyPointRepo.UpdateLegDistance(kp.ID, 0); err != nil {
+				return err
+			}
+		}
+		if err := tourRepo.ReplaceSuggestedDurations(tourID, nil); err != nil {
+			return err
+		}
+		return tourRepo.UpdateRoute(tourID, 0, false)
+	}
+
+	points := make([]interfaces.Coordinate, len(keyPoints))
+	for i, kp := range keyPoints {
+		points[i] = interfaces.Coordinate{Latitude: kp.Latitude, Longitude: kp.Longitude}
+	}
+
+	// distanca ture i deonice se racunaju po pesackoj ruti
+	walking, err := p.route(points)
+	if err != nil {
+		return err
+	}
+	return storeRoute(tourRepo, keyPointRepo, tourID, keyPoints, walking)
+}
+
+// ApplyRoute cuva vec poznatu rutu ture (npr. track iz uvezenog fajla) bez poziva routing providera
+func (p *RoutePlanner) ApplyRoute(tourRepo *repository.TourRepository, keyPointRepo *repository.KeyPointRepository, tourID uint, walking *interfaces.RouteEstimate) error {
+	keyPoints, err := keyPointRepo.FindByTourID(tourID)
+	if err != nil {
+		return err
+	}
+	return storeRoute(tourRepo, keyPointRepo, tourID, keyPoints, walking)
+}
+
+// storeRoute cuva distancu ture, deonice keypointa i predlozena trajanja izvedena iz iste pesacke rute
+func storeRoute(tourRepo *repository.TourRepository, keyPointRepo *repository.KeyPointRepository, tourID uint, keyPoints []models.KeyPoint, walking *interfaces.RouteEstimate) error {
+	var durations []models.TourDuration
+	for _, transport := range suggestedTransportTypes {
+		durations = append(durations, models.TourDuration{
+			TourID:        tourID,
+			TransportType: transport,
+			DurationMin:   suggestedDuration(walking, transport),
+			Suggested:     true,
+		})
+	}
+
+	for i, kp := range keyPoints {
+		leg := 0.0
+		if i > 0 && i-1 < len(walking.LegDistancesKm) {
+			leg = walking.LegDistancesKm[i-1]
+		}
+		if err := keyPointRepo.UpdateLegDistance(kp.ID, leg); err != nil {
+			return err
+		}
+	}
+	if err := tourRepo.ReplaceSuggestedDurations(tourID, durations); err != nil {
+		return err
+	}
+	return tourRepo.UpdateRoute(tourID, walking.DistanceKm, walking.Estimated)
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"tour-service/internal/interfaces"
+	"tour-service/internal/models"
+)
+
+// fakeRoutingProvider broji pozive i vraca zadatu rutu ili gresku
+type fakeRoutingProvider struct {
+	estimate *interfaces.RouteEstimate
+	err      error
+	calls    []models.TransportType
+}
+
+func (f *fakeRoutingProvider) Route(transport models.TransportType, points []interfaces.Coordinate) (*interfaces.RouteEstimate, error) {
+	f.calls = append(f.calls, transport)
+	return f.estimate, f.err
+}
+
+var testRoutePoints = []interfaces.Coordinate{
+	{Latitude: 45.2551, Longitude: 19.8451},
+	{Latitude: 45.2517, Longitude: 19.8624},
+	{Latitude: 45.2461, Longitude: 19.8517},
+}
+
+func TestRoutePlannerUsesSingleRoutedRequest(t *testing.T) {
+	provider := &fakeRoutingProvider{estimate: &interfaces.RouteEstimate{
+		LegDistancesKm: []float64{1.6, 1.1},
+		DistanceKm:     2.7,
+		DurationMin:    35,
+	}}
+	planner := NewRoutePlanner(provider)
+
+	estimate, err := planner.route(testRoutePoints)
+	if err != nil {
+		t.Fatalf("route returned error: %v", err)
+	}
+	if len(provider.calls) != 1 || provider.calls[0] != models.Walking {
+		t.Errorf("expected one walking request, got %v", provider.calls)
+	}
+	if estimate.Estimated {
+		t.Error("routed result must not be marked as estimated")
+	}
+	if got := suggestedDuration(estimate, models.Walking); got != 35 {
+		t.Errorf("walking duration = %d, want routed 35", got)
+	}
+	if got := suggestedDuration(estimate, models.Car); got != 4 {
+		t.Errorf("car duration = %d, want 4", got)
+	}
+}
+
+func TestRoutePlannerMarksFallbackAsEstimated(t *testing.T) {
+	provider := &fakeRoutingProvider{err: errors.New("osrm unavailable")}
+	planner := NewRoutePlanner(provider)
+
+	estimate, err := planner.route(testRoutePoints)
+	if err != nil {
+		t.Fatalf("route returned error: %v", err)
+	}
+	if !estimate.Estimated {
+		t.Error("straight-line fallback must be marked as estimated")
+	}
+	if len(estimate.LegDistancesKm) != len(testRoutePoints)-1 || estimate.DistanceKm <= 0 {
+		t.Errorf("unexpected fallback estimate: %+v", estimate)
+	}
+}
+
+func TestTrackRouteMeasuresLegsAlongTrack(t *testing.T) {
+	// track skrece, pa je deonica po tracku duza od vazdusne linije izmedju keypointa
+	track := []routePoint{
+		{Latitude: 45.000, Longitude: 19.000},
+		{Latitude: 45.010, Longitude: 19.000},
+		{Latitude: 45.010, Longitude: 19.010},
+		{Latitude: 45.000, Longitude: 19.010},
+		{Latitude: 45.000, Longitude: 19.020},
+	}
+	keyPoints := []routePoint{track[0], track[3], track[4]}
+
+	estimate := trackRoute(track, keyPoints)
+	if estimate.Estimated {
+		t.Error("imported track should not be marked as a straight-line estimate")
+	}
+	if want := pathDistance(track); estimate.DistanceKm != want {
+		t.Errorf("expected track distance %.4f, got %.4f", want, estimate.DistanceKm)
+	}
+	if len(estimate.LegDistancesKm) != 2 {
+		t.Fatalf("expected 2 legs, got %v", estimate.LegDistancesKm)
+	}
+	if want := pathDistance(track[:4]); estimate.LegDistancesKm[0] != want {
+		t.Errorf("expected first leg %.4f along the track, got %.4f", want, estimate.LegDistancesKm[0])
+	}
+	if sum := estimate.LegDistancesKm[0] + estimate.LegDistancesKm[1]; sum != estimate.DistanceKm {
+		t.Errorf("legs add up to %.4f, expected %.4f", sum, estimate.DistanceKm)
+	}
+	if d := suggestedDuration(estimate, models.Walking); d != int(math.Ceil(estimate.DistanceKm/averageSpeedsKmh[models.Walking]*60)) {
+		t.Errorf("expected walking duration derived from the track distance, got %d", d)
+	}
+}
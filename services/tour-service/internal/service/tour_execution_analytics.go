@@ -0,0 +1,196 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+	"tour-service/internal/dto"
+	"tour-service/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTourNotFound       = errors.New("tour not found")
+	ErrAnalyticsForbidden = errors.New("forbidden: only the tour author or an administrator can view analytics")
+)
+
+// GetTourAnalytics racuna statistiku izvrsavanja ture: brojeve po periodima, stopu zavrsavanja,
+// medijanu trajanja i funnel po keypointima. Vidi je samo autor ture i administrator.
+func (s *TourExecutionService) GetTourAnalytics(tourID uint, userID uint, isAdmin bool, query dto.TourAnalyticsQuery) (*dto.TourAnalyticsResponse, error) {
+	interval := query.Interval
+	if interval == "" {
+		interval = "day"
+	}
+	if interval != "day" && interval != "week" && interval != "month" {
+		return nil, fmt.Errorf("invalid interval: %s", interval)
+	}
+
+	authorID, err := s.repo.GetTourAuthorID(tourID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTourNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && authorID != userID {
+		return nil, ErrAnalyticsForbidden
+	}
+
+	executions, err := s.repo.GetExecutionsByTourStartedBetween(tourID, query.From, query.To)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.TourAnalyticsResponse{
+		TourID:   tourID,
+		Interval: interval,
+		Started:  len(executions),
+		Timeline: []dto.ExecutionPeriodStats{},
+	}
+
+	periods := map[time.Time]*dto.ExecutionPeriodStats{}
+	period := func(t time.Time) *dto.ExecutionPeriodStats {
+		start := periodStart(t, interval)
+		if periods[start] == nil {
+			periods[start] = &dto.ExecutionPeriodStats{PeriodStart: start}
+		}
+		return periods[start]
+	}
+
+	var durations []time.Duration
+	for _, e := range executions {
+		period(e.StartTime).Started++
+		switch e.Status {
+		case models.ExecutionCompleted:
+			response.Completed++
+			if e.EndTime != nil {
+				period(*e.EndTime).Completed++
+				durations = append(durations, e.EndTime.Sub(e.StartTime))
+			}
+		case models.ExecutionAbandoned:
+			response.Abandoned++
+			if e.EndTime != nil {
+				period(*e.EndTime).Abandoned++
+			}
+		default:
+			response.InProgress++
+		}
+	}
+
+	for _, p := range periods {
+		response.Timeline = append(response.Timeline, *p)
+	}
+	sort.Slice(response.Timeline, func(i, j int) bool {
+		return response.Timeline[i].PeriodStart.Before(response.Timeline[j].PeriodStart)
+	})
+
+	if finished := response.Completed + response.Abandoned; finished > 0 {
+		response.CompletionRate = float64(response.Completed) / float64(finished)
+	}
+	if median, ok := medianDuration(durations); ok {
+		seconds := int64(median.Seconds())
+		response.MedianCompletionSeconds = &seconds
+	}
+
+	response.Funnel, err = s.executionFunnel(tourID, executions)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// executionFunnel broji koliko izvrsavanja je stiglo do keypointa na svakoj poziciji ture.
+// Keypointi se razlikuju po verzijama ture, pa se ID iz CompletedKeyPoints prevodi u poziciju
+// preko verzije na kojoj je izvrsavanje zapoceto.
+func (s *TourExecutionService) executionFunnel(tourID uint, executions []models.TourExecution) ([]dto.FunnelStep, error) {
+	current, err := s.repo.GetTourWithKeyPoints(tourID)
+	if err != nil {
+		return nil, err
+	}
+
+	orderByVersion := map[int]map[int64]int{}
+	orders := func(version int) map[int64]int {
+		if byID, ok := orderByVersion[version]; ok {
+			return byID
+		}
+		keyPoints := current.KeyPoints
+		if version > 0 {
+			tour, err := s.repo.GetTourByVersion(tourID, version)
+			if err == nil {
+				keyPoints = tour.KeyPoints
+			} else {
+				log.Printf("Tour %d version %d not found, using current key points for funnel: %v", tourID, version, err)
+			}
+		}
+		byID := make(map[int64]int, len(keyPoints))
+		for i, kp := range keyPoints {
+			byID[int64(kp.ID)] = i + 1
+		}
+		orderByVersion[version] = byID
+		return byID
+	}
+
+	reached := map[int]int{}
+	maxOrder := len(current.KeyPoints)
+	for _, e := range executions {
+		byID := orders(e.TourVersion)
+		for _, id := range e.CompletedKeyPoints {
+			if order, ok := byID[id]; ok {
+				reached[order]++
+				if order > maxOrder {
+					maxOrder = order
+				}
+			}
+		}
+	}
+
+	return funnelSteps(reached, maxOrder, len(executions), current.KeyPoints), nil
+}
+
+// funnelSteps pravi korake funnela iz broja izvrsavanja koja su stigla do svake pozicije. Kod FreeOrder tura
+// kasniji keypoint moze biti dostignut cesce od prethodnog, pa DropOff tada nije negativan nego 0.
+func funnelSteps(reached map[int]int, maxOrder int, total int, keyPoints []models.KeyPoint) []dto.FunnelStep {
+	funnel := make([]dto.FunnelStep, 0, maxOrder)
+	previous := total
+	for order := 1; order <= maxOrder; order++ {
+		step := dto.FunnelStep{Order: order, Reached: reached[order], DropOff: max(previous-reached[order], 0)}
+		if order <= len(keyPoints) {
+			step.Name = keyPoints[order-1].Name
+		}
+		if total > 0 {
+			step.Percent = float64(step.Reached) * 100 / float64(total)
+		}
+		funnel = append(funnel, step)
+		previous = step.Reached
+	}
+	return funnel
+}
+
+// periodStart vraca pocetak dana, nedelje (ponedeljak) ili meseca u UTC
+func periodStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case "week":
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+func medianDuration(durations []time.Duration) (time.Duration, bool) {
+	if len(durations) == 0 {
+		return 0, false
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	middle := len(durations) / 2
+	if len(durations)%2 == 1 {
+		return durations[middle], true
+	}
+	return (durations[middle-1] + durations[middle]) / 2, true
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"testing"
+	"tour-service/internal/models"
+)
+
+func TestFunnelStepsNeverReportNegativeDropOff(t *testing.T) {
+	keyPoints := []models.KeyPoint{{Name: "Start"}, {Name: "Tvrdjava"}, {Name: "Kraj"}}
+	// FreeOrder: drugi keypoint je obidjen cesce od prvog
+	reached := map[int]int{1: 4, 2: 7, 3: 2}
+
+	steps := funnelSteps(reached, 3, 10, keyPoints)
+	expected := []struct {
+		name    string
+		dropOff int
+	}{{"Start", 6}, {"Tvrdjava", 0}, {"Kraj", 5}}
+	if len(steps) != len(expected) {
+		t.Fatalf("expected %d steps, got %d", len(expected), len(steps))
+	}
+	for i, want := range expected {
+		if steps[i].Name != want.name || steps[i].DropOff != want.dropOff {
+			t.Errorf("step %d: expected %s with drop-off %d, got %s with %d", i+1, want.name, want.dropOff, steps[i].Name, steps[i].DropOff)
+		}
+	}
+	if steps[1].Percent != 70 {
+		t.Errorf("expected 70%% reaching step 2, got %v", steps[1].Percent)
+	}
+}
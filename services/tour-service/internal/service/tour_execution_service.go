@@ -2,24 +2,37 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"math"
 	"time"
+	"tour-service/internal/dto"
 	"tour-service/internal/models"
 	"tour-service/internal/repository"
 
 	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrExecutionNotFound = errors.New("tour execution not found")
+	ErrNotExecutionOwner = errors.New("forbidden: tour execution belongs to another tourist")
+	ErrExecutionConflict = errors.New("invalid tour execution state")
 )
 
 type TourExecutionService struct {
 	repo            *repository.TourExecutionRepository
 	purchaseChecker PurchaseChecker
+	events          *ExecutionEventHub
+	badges          *BadgeService
 }
 
-func NewTourExecutionService(repo *repository.TourExecutionRepository, purchaseChecker PurchaseChecker) *TourExecutionService {
+func NewTourExecutionService(repo *repository.TourExecutionRepository, purchaseChecker PurchaseChecker, events *ExecutionEventHub, badges *BadgeService) *TourExecutionService {
 	return &TourExecutionService{
 		repo:            repo,
 		purchaseChecker: purchaseChecker,
+		events:          events,
+		badges:          badges,
 	}
 }
 
@@ -36,9 +49,15 @@ func (s *TourExecutionService) StartTour(tourID uint, touristID uint, startLat,
 		return nil, errors.New("must purchase tour before starting")
 	}
 
+	version, err := s.repo.GetTourVersion(tourID)
+	if err != nil {
+		return nil, errors.New("tour not found")
+	}
+
 	execution := &models.TourExecution{
 		TourID:             tourID,
 		TouristID:          touristID,
+		TourVersion:        version,
 		Status:             models.ExecutionStarted,
 		StartTime:          time.Now(),
 		LastActivity:       time.Now(),
@@ -50,70 +69,270 @@ func (s *TourExecutionService) StartTour(tourID uint, touristID uint, startLat,
 	return s.repo.Create(execution)
 }
 
-func (s *TourExecutionService) CheckPosition(executionID uint, currentLat, currentLng float64) ([]int, error) {
-	execution, err := s.repo.GetByID(executionID)
+// CheckPosition zavrsava keypointe u cijem je radiusu turista. Kod Sequential tura moze da se zavrsi
+// samo sledeci neposeceni keypoint po Order, kod FreeOrder bilo koji. Vraca i sledeci keypoint i udaljenost do njega.
+func (s *TourExecutionService) CheckPosition(executionID uint, touristID uint, currentLat, currentLng float64) (*dto.CheckPositionResponse, error) {
+	var response *dto.CheckPositionResponse
+	err := s.withOwnedExecution(executionID, touristID, func(repo *repository.TourExecutionRepository, execution *models.TourExecution) error {
+		if execution.Status != models.ExecutionStarted {
+			return fmt.Errorf("%w: execution is %s", ErrExecutionConflict, execution.Status)
+		}
+
+		err := repo.CreateTrackPoint(&models.ExecutionTrackPoint{
+			ExecutionID: execution.ID,
+			Latitude:    currentLat,
+			Longitude:   currentLng,
+			RecordedAt:  time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		tour, err := tourForExecution(repo, execution)
+		if err != nil {
+			return err
+		}
+		response, err = checkKeyPoints(repo, execution, tour, currentLat, currentLng)
+		if err != nil {
+			return err
+		}
+		response.Revealed, err = revealNewlyCompleted(repo, execution, tour, response.NewlyCompleted)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	keyPoints, err := s.repo.GetKeyPointsByTour(execution.TourID)
-	if err != nil {
+	s.publishProgress(executionID, response)
+	return response, nil
+}
+
+// publishProgress javlja live kanalu zavrsene keypointe i sta turista sledece treba da obidje
+func (s *TourExecutionService) publishProgress(executionID uint, response *dto.CheckPositionResponse) {
+	if len(response.NewlyCompleted) == 0 {
+		return
+	}
+	for _, keyPointID := range response.NewlyCompleted {
+		data := map[string]interface{}{"keyPointId": keyPointID}
+		for _, revealed := range response.Revealed {
+			if revealed.KeyPointID == uint(keyPointID) {
+				data["secret"] = revealed
+			}
+		}
+		s.events.Publish(executionID, EventKeyPointReached, data)
+	}
+	if response.NextKeyPoint != nil {
+		s.events.Publish(executionID, EventNextKeyPoint, map[string]interface{}{
+			"keyPoint":   response.NextKeyPoint,
+			"distanceKm": response.DistanceToNextKm,
+		})
+	} else {
+		s.events.Publish(executionID, EventCompleteEligible, nil)
+	}
+}
+
+// checkKeyPoints zavrsava keypointe ture u cijem je radiusu pozicija i cuva izvrsavanje
+func checkKeyPoints(repo *repository.TourExecutionRepository, execution *models.TourExecution, tour *models.Tour, currentLat, currentLng float64) (*dto.CheckPositionResponse, error) {
+	newlyCompleted := completeKeyPointsAt(execution, tour, currentLat, currentLng)
+
+	// svaka prijavljena pozicija je aktivnost, pa izvrsavanje u pokretu ne postaje napusteno
+	execution.LastActivity = time.Now()
+	if _, err := repo.Update(execution); err != nil {
 		return nil, err
 	}
 
-	var newlyCompleted []int
+	return positionResponse(execution, tour, newlyCompleted, currentLat, currentLng), nil
+}
 
-	for _, kp := range keyPoints {
+// completeKeyPointsAt dodaje u izvrsavanje keypointe u cijem je radiusu pozicija i vraca novo zavrsene
+func completeKeyPointsAt(execution *models.TourExecution, tour *models.Tour, lat, lng float64) []int {
+	mode := completionModeOf(tour)
+	newlyCompleted := []int{}
+
+	for _, kp := range tour.KeyPoints {
 		if contains(execution.CompletedKeyPoints, int64(kp.ID)) {
 			continue
 		}
 
-		distance := calculateDistance(currentLat, currentLng, kp.Latitude, kp.Longitude)
+		distance := calculateDistance(lat, lng, kp.Latitude, kp.Longitude)
 
-		if distance <= 0.05 {
+		if distance*1000 <= arrivalRadiusOrDefault(kp.ArrivalRadius) {
 			execution.CompletedKeyPoints = append(execution.CompletedKeyPoints, int64(kp.ID))
 			newlyCompleted = append(newlyCompleted, int(kp.ID))
+		} else if mode == models.Sequential {
+			break
 		}
 	}
+	return newlyCompleted
+}
 
-	if len(newlyCompleted) > 0 {
-		execution.LastActivity = time.Now()
-		_, err = s.repo.Update(execution)
-		if err != nil {
-			return nil, err
+// positionResponse opisuje napredak izvrsavanja posle pozicije lat, lng
+func positionResponse(execution *models.TourExecution, tour *models.Tour, newlyCompleted []int, lat, lng float64) *dto.CheckPositionResponse {
+	mode := completionModeOf(tour)
+	response := &dto.CheckPositionResponse{
+		NewlyCompleted:     newlyCompleted,
+		CompletedKeyPoints: execution.CompletedKeyPoints,
+		CompletionMode:     string(mode),
+	}
+	if next, distance := nextKeyPoint(tour.KeyPoints, execution.CompletedKeyPoints, mode, lat, lng); next != nil {
+		response.NextKeyPoint = next
+		response.DistanceToNextKm = distance
+	}
+	return response
+}
+
+// nextKeyPoint vraca keypoint ka kome turista treba da ide: kod Sequential prvi neposeceni po Order,
+// kod FreeOrder najblizi neposeceni
+func nextKeyPoint(keyPoints []models.KeyPoint, completed pq.Int64Array, mode models.CompletionMode, lat, lng float64) (*models.KeyPoint, float64) {
+	var next *models.KeyPoint
+	nextDistance := 0.0
+	for i := range keyPoints {
+		if contains(completed, int64(keyPoints[i].ID)) {
+			continue
+		}
+		distance := calculateDistance(lat, lng, keyPoints[i].Latitude, keyPoints[i].Longitude)
+		if mode == models.Sequential {
+			return &keyPoints[i], distance
+		}
+		if next == nil || distance < nextDistance {
+			next, nextDistance = &keyPoints[i], distance
 		}
 	}
+	return next, nextDistance
+}
 
-	return newlyCompleted, nil
+// tourForExecution vraca turu sa keypointima u verziji na kojoj je izvrsavanje zapoceto,
+// da kasnije izmene ture ne bi promenile ono sto turista obilazi
+func tourForExecution(repo *repository.TourExecutionRepository, execution *models.TourExecution) (*models.Tour, error) {
+	if execution.TourVersion > 0 {
+		tour, err := repo.GetTourByVersion(execution.TourID, execution.TourVersion)
+		if err == nil {
+			hideSecrets(tour.KeyPoints)
+			return tour, nil
+		}
+		log.Printf("Tour %d version %d not found, using current key points: %v", execution.TourID, execution.TourVersion, err)
+	}
+	return repo.GetTourWithKeyPoints(execution.TourID)
 }
 
-func (s *TourExecutionService) CompleteTour(executionID uint) error {
-	execution, err := s.repo.GetByID(executionID)
+// completionModeOf vraca nacin obilaska ture, starije verzije bez njega se obilaze bilo kojim redosledom
+func completionModeOf(tour *models.Tour) models.CompletionMode {
+	if tour.CompletionMode == "" {
+		return models.FreeOrder
+	}
+	return tour.CompletionMode
+}
+
+// completedCount vraca koliko keypointa ture je zavrseno u izvrsavanju
+func completedCount(keyPoints []models.KeyPoint, completed pq.Int64Array) int {
+	count := 0
+	for _, kp := range keyPoints {
+		if contains(completed, int64(kp.ID)) {
+			count++
+		}
+	}
+	return count
+}
+
+// executionProgress vraca udeo zavrsenih keypointa izvrsavanja (0-1), zavrseno izvrsavanje je uvek 1
+func executionProgress(repo *repository.TourExecutionRepository, execution *models.TourExecution) (float64, error) {
+	if execution.Status == models.ExecutionCompleted {
+		return 1, nil
+	}
+	tour, err := tourForExecution(repo, execution)
 	if err != nil {
-		return err
+		return 0, err
+	}
+	if len(tour.KeyPoints) == 0 {
+		return 0, nil
+	}
+	return float64(completedCount(tour.KeyPoints, execution.CompletedKeyPoints)) / float64(len(tour.KeyPoints)), nil
+}
+
+// withOwnedExecution u transakciji zakljucava izvrsavanje i proverava da pripada turisti,
+// da se istovremeni zahtevi (npr. complete i abandon) ne bi preplitali
+func (s *TourExecutionService) withOwnedExecution(executionID uint, touristID uint, fn func(repo *repository.TourExecutionRepository, execution *models.TourExecution) error) error {
+	return s.repo.DB.Transaction(func(tx *gorm.DB) error {
+		repo := repository.NewTourExecutionRepository(tx)
+		execution, err := repo.GetByIDForUpdate(executionID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrExecutionNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if execution.TouristID != touristID {
+			return ErrNotExecutionOwner
+		}
+		return fn(repo, execution)
+	})
+}
+
+// finishExecution prevodi izvrsavanje u zavrsno stanje ako je prelaz dozvoljen
+func finishExecution(repo *repository.TourExecutionRepository, execution *models.TourExecution, status models.TourExecutionStatus) error {
+	if !execution.Status.CanTransitionTo(status) {
+		return fmt.Errorf("%w: execution is %s and cannot become %s", ErrExecutionConflict, execution.Status, status)
 	}
 
 	endTime := time.Now()
-	execution.Status = models.ExecutionCompleted
+	execution.Status = status
 	execution.EndTime = &endTime
 	execution.LastActivity = endTime
 
-	_, err = s.repo.Update(execution)
+	_, err := repo.Update(execution)
 	return err
 }
 
-func (s *TourExecutionService) AbandonTour(executionID uint) error {
-	execution, err := s.repo.GetByID(executionID)
+// CompleteTour zavrsava izvrsavanje tek kada su obidjeni svi keypointi ture
+func (s *TourExecutionService) CompleteTour(executionID uint, touristID uint) error {
+	err := s.withOwnedExecution(executionID, touristID, func(repo *repository.TourExecutionRepository, execution *models.TourExecution) error {
+		if !execution.Status.CanTransitionTo(models.ExecutionCompleted) {
+			return fmt.Errorf("%w: execution is %s and cannot become %s", ErrExecutionConflict, execution.Status, models.ExecutionCompleted)
+		}
+
+		tour, err := tourForExecution(repo, execution)
+		if err != nil {
+			return err
+		}
+		done := completedCount(tour.KeyPoints, execution.CompletedKeyPoints)
+		if len(tour.KeyPoints) == 0 || done < len(tour.KeyPoints) {
+			return fmt.Errorf("%w: %d of %d key points completed", ErrExecutionConflict, done, len(tour.KeyPoints))
+		}
+
+		return finishExecution(repo, execution, models.ExecutionCompleted)
+	})
 	if err != nil {
 		return err
 	}
 
-	endTime := time.Now()
-	execution.Status = models.ExecutionAbandoned
-	execution.EndTime = &endTime
-	execution.LastActivity = endTime
+	// bedzevi se salju pre "completed", jer live kanal posle zavrsnog dogadjaja zatvara vezu
+	badges := s.awardBadges(executionID, touristID)
+	s.events.Publish(executionID, EventCompleted, map[string]interface{}{"badges": badges})
+	return nil
+}
+
+// awardBadges dodeljuje bedzeve osvojene zavrsavanjem ture i vraca njihove kodove. Greska ovde ne ponistava
+// zavrsavanje, bedzevi se ponovo proveravaju pri sledecem zavrsenom izvrsavanju.
+func (s *TourExecutionService) awardBadges(executionID uint, touristID uint) []string {
+	earned, err := s.badges.EvaluateBadges(touristID)
+	if err != nil {
+		log.Printf("Failed to evaluate badges for user %d: %v", touristID, err)
+	}
+	codes := make([]string, 0, len(earned))
+	for _, badge := range earned {
+		s.events.Publish(executionID, EventBadgeEarned, map[string]interface{}{"code": badge.Code, "earnedAt": badge.EarnedAt})
+		codes = append(codes, badge.Code)
+	}
+	return codes
+}
 
-	_, err = s.repo.Update(execution)
+func (s *TourExecutionService) AbandonTour(executionID uint, touristID uint) error {
+	err := s.withOwnedExecution(executionID, touristID, func(repo *repository.TourExecutionRepository, execution *models.TourExecution) error {
+		return finishExecution(repo, execution, models.ExecutionAbandoned)
+	})
+	if err == nil {
+		s.events.Publish(executionID, EventAbandoned, map[string]interface{}{"autoAbandoned": false})
+	}
 	return err
 }
 
@@ -121,8 +340,28 @@ func (s *TourExecutionService) GetActiveExecution(touristID uint, tourID uint) (
 	return s.repo.GetActiveExecution(touristID, tourID)
 }
 
-func (s *TourExecutionService) GetExecutionDetails(executionID uint) (*models.TourExecution, error) {
-	return s.repo.GetByID(executionID)
+// GetExecutionDetails vraca izvrsavanje turisti koji ga je pokrenuo, autoru ture ili administratoru
+func (s *TourExecutionService) GetExecutionDetails(executionID uint, userID uint, isAdmin bool) (*models.TourExecution, error) {
+	return s.executionForViewer(executionID, userID, isAdmin)
+}
+
+// executionForViewer ucitava izvrsavanje i proverava da li korisnik sme da ga vidi
+func (s *TourExecutionService) executionForViewer(executionID uint, userID uint, isAdmin bool) (*models.TourExecution, error) {
+	execution, err := s.repo.GetByID(executionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrExecutionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !isAdmin && execution.TouristID != userID {
+		authorID, err := s.repo.GetTourAuthorID(execution.TourID)
+		if err != nil || authorID != userID {
+			return nil, ErrNotExecutionOwner
+		}
+	}
+	return execution, nil
 }
 
 func calculateDistance(lat1, lng1, lat2, lng2 float64) float64 {
@@ -150,6 +389,17 @@ func contains(slice pq.Int64Array, item int64) bool {
 	return false
 }
 
-func (s *TourExecutionService) GetExecutionsByTour(tourID uint) ([]models.TourExecution, error) {
-    return s.repo.GetExecutionsByTour(tourID)
+// GetExecutionsByTour vraca sva izvrsavanja ture autoru i administratoru, a ostalima samo njihova
+func (s *TourExecutionService) GetExecutionsByTour(tourID uint, userID uint, isAdmin bool) ([]models.TourExecution, error) {
+    authorID, err := s.repo.GetTourAuthorID(tourID)
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, ErrTourNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+    if isAdmin || authorID == userID {
+        return s.repo.GetExecutionsByTour(tourID)
+    }
+    return s.repo.GetExecutionsByTouristAndTour(userID, tourID)
 }
\ No newline at end of file
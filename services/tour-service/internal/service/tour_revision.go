@@ -0,0 +1,233 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"tour-service/internal/dto"
+	"tour-service/internal/models"
+	"tour-service/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// CreateRevision kreira draft reviziju publishovane ili arhivirane ture koju autor moze da menja
+// bez uticaja na turu koju su turisti kupili. Ako revizija vec postoji, vraca nju.
+func (s *TourService) CreateRevision(tourID uint, authorID uint) (*models.Tour, error) {
+	tour, err := s.Repo.FindByID(tourID)
+	if err != nil {
+		return nil, errors.New("tour not found")
+	}
+	if tour.AuthorID != authorID {
+		return nil, errors.New("unauthorized: not tour author")
+	}
+	if tour.RevisionOf != nil {
+		return nil, errors.New("tour is already a revision")
+	}
+	if tour.Status == models.Draft {
+		return nil, errors.New("draft tours can be edited directly")
+	}
+
+	existing, err := s.Repo.FindRevision(tourID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return s.Repo.FindByIDWithRelations(existing.ID)
+	}
+
+	revision := &models.Tour{
+		AuthorID:       tour.AuthorID,
+		Name:           tour.Name,
+		Description:    tour.Description,
+		Difficulty:     tour.Difficulty,
+		Tags:           tour.Tags,
+		Status:         models.Draft,
+		Price:          tour.Price,
+		Distance:       tour.Distance,
+		RouteEstimated: tour.RouteEstimated,
+		CompletionMode: tour.CompletionMode,
+		Version:        tour.Version,
+		RevisionOf:     &tour.ID,
+	}
+	err = s.Repo.DB.Transaction(func(tx *gorm.DB) error {
+		tourRepo := repository.NewTourRepository(tx)
+		if err := tourRepo.Create(revision); err != nil {
+			return err
+		}
+		return tourRepo.CopyRoute(tour.ID, revision.ID)
+	})
+	if err != nil && isDuplicateKey(err) {
+		// istovremeni zahtev je vec otvorio reviziju ove ture
+		existing, findErr := s.Repo.FindRevision(tourID)
+		if findErr != nil || existing == nil {
+			return nil, err
+		}
+		return s.Repo.FindByIDWithRelations(existing.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindByIDWithRelations(revision.ID)
+}
+
+// CloneTour kopira turu autora (sa keypointima i trajanjima) u novi draft sa novim imenom.
+// Datumi publishovanja/arhiviranja, verzija i istorija cena kreću ispočetka.
+func (s *TourService) CloneTour(tourID uint, authorID uint, req dto.CloneTourRequest) (*models.Tour, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: tour name is required", ErrInvalidRequest)
+	}
+
+	source, err := findTour(s.Repo.FindByID, tourID)
+	if err != nil {
+		return nil, err
+	}
+	if source.AuthorID != authorID {
+		return nil, ErrNotTourAuthor
+	}
+
+	clone := &models.Tour{
+		AuthorID:       authorID,
+		Name:           name,
+		Description:    source.Description,
+		Difficulty:     source.Difficulty,
+		Tags:           source.Tags,
+		Status:         models.Draft,
+		Price:          source.Price,
+		Distance:       source.Distance,
+		RouteEstimated: source.RouteEstimated,
+		CompletionMode: source.CompletionMode,
+	}
+	err = s.Repo.DB.Transaction(func(tx *gorm.DB) error {
+		tourRepo := repository.NewTourRepository(tx)
+		if err := tourRepo.Create(clone); err != nil {
+			return err
+		}
+		if err := tourRepo.CopyRoute(source.ID, clone.ID); err != nil {
+			return err
+		}
+		return tourRepo.CreatePriceHistory(&models.TourPriceHistory{
+			TourID:    clone.ID,
+			NewPrice:  clone.Price,
+			ChangedBy: authorID,
+			ChangedAt: clone.CreatedAt,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindByIDWithRelations(clone.ID)
+}
+
+// publishRevision atomicno prenosi izmene revizije na originalnu turu, podize verziju i brise reviziju
+func (s *TourService) publishRevision(revision *models.Tour) (*models.Tour, error) {
+	originalID := *revision.RevisionOf
+
+	err := s.Repo.DB.Transaction(func(tx *gorm.DB) error {
+		tourRepo := repository.NewTourRepository(tx)
+
+		original, err := tourRepo.FindByIDForUpdate(originalID)
+		if err != nil {
+			return errors.New("original tour not found")
+		}
+		if original.IsDeleted {
+			return errors.New("original tour was deleted")
+		}
+		if original.Status == models.Draft {
+			return errors.New("original tour is not published")
+		}
+
+		version := original.Version + 1
+		fields := map[string]interface{}{
+			"name":            revision.Name,
+			"description":     revision.Description,
+			"difficulty":      revision.Difficulty,
+			"tags":            revision.Tags,
+			"price":           revision.Price,
+			"distance":        revision.Distance,
+			"route_estimated": revision.RouteEstimated,
+			"completion_mode": revision.CompletionMode,
+			"version":         version,
+		}
+		if err := tourRepo.UpdateFields(originalID, fields); err != nil {
+			return err
+		}
+		if original.Price != revision.Price {
+			err := tourRepo.CreatePriceHistory(&models.TourPriceHistory{
+				TourID:    originalID,
+				OldPrice:  original.Price,
+				NewPrice:  revision.Price,
+				ChangedBy: revision.AuthorID,
+				ChangedAt: time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := tourRepo.MoveRoute(revision.ID, originalID); err != nil {
+			return err
+		}
+		if err := tourRepo.Delete(revision.ID); err != nil {
+			return err
+		}
+		return createVersionSnapshot(tourRepo, originalID, version)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindByIDWithRelations(originalID)
+}
+
+// createVersionSnapshot cuva trenutno stanje ture (sa keypointima, njihovim tajnama i trajanjima) kao nepromenljivu verziju.
+// Tajne ostaju u snapshotu da bi ih turisti koji obilaze tu verziju dobili po dolasku na keypoint.
+func createVersionSnapshot(tourRepo *repository.TourRepository, tourID uint, version int) error {
+	tour, err := tourRepo.FindByIDWithSecrets(tourID)
+	if err != nil {
+		return err
+	}
+	snapshot, err := json.Marshal(tour)
+	if err != nil {
+		return err
+	}
+	return tourRepo.CreateVersion(&models.TourVersion{
+		TourID:      tourID,
+		Version:     version,
+		Snapshot:    string(snapshot),
+		PublishedAt: time.Now(),
+	})
+}
+
+// GetTourVersions vraca listu publishovanih verzija ture
+func (s *TourService) GetTourVersions(tourID uint) ([]models.TourVersion, error) {
+	if _, err := s.Repo.FindByID(tourID); err != nil {
+		return nil, errors.New("tour not found")
+	}
+	return s.Repo.FindVersions(tourID)
+}
+
+// GetTourVersion vraca turu onakvu kakva je bila u datoj verziji; vazi isto pravilo vidljivosti kao GetTourByID
+func (s *TourService) GetTourVersion(tourID uint, version int, userID uint, authHeader string) (*models.Tour, error) {
+	tourVersion, err := s.Repo.FindVersion(tourID, version)
+	if err != nil {
+		return nil, errors.New("tour version not found")
+	}
+
+	var tour models.Tour
+	if err := json.Unmarshal([]byte(tourVersion.Snapshot), &tour); err != nil {
+		return nil, err
+	}
+	tour.Version = tourVersion.Version
+	hideSecrets(tour.KeyPoints)
+
+	if len(tour.KeyPoints) > 0 && !s.hasFullAccess(&tour, userID, authHeader) {
+		tour.KeyPoints = tour.KeyPoints[:1]
+	}
+	return &tour, nil
+}
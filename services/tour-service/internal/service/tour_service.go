@@ -4,28 +4,58 @@ import (
 	"errors"
 	"math"
 	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 	"tour-service/internal/dto"
 	"tour-service/internal/models"
 	"tour-service/internal/repository"
 	"tour-service/internal/interfaces" 
+
+	"gorm.io/gorm"
 )
 
 type TourService struct {
 	Repo *repository.TourRepository
 	PurchaseChecker interfaces.PurchaseChecker
+	Routes *RoutePlanner
 }
 
 // kreira novu instancu servisa
-func NewTourService(repo *repository.TourRepository, checker interfaces.PurchaseChecker) *TourService { 
+func NewTourService(repo *repository.TourRepository, checker interfaces.PurchaseChecker, routes *RoutePlanner) *TourService { 
 	return &TourService{
 	Repo: repo,
 	PurchaseChecker: checker,
+	Routes: routes,
 	}
 }
 
 // obrađuje DTO, primenjuje pravila i poziva repozitorijum
 // kreira turu SA keypointsima
 func (s *TourService) CreateTour(authorID uint, req dto.CreateTourRequest) (*models.Tour, error) {
+	var tour *models.Tour
+	err := s.Repo.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		tour, err = createDraftTour(tx, authorID, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// racuna distancu ako ima 2+ keypointa
+	if len(req.KeyPoints) >= 2 {
+		s.CalculateDistance(tour.ID)
+	}
+
+	return tour, nil
+}
+
+// createDraftTour validira zahtev i u transakciji tx cuva turu, pocetnu cenu i keypointe,
+// pa neuspeh ne ostavlja turu bez istorije cena ili sa delom keypointa
+func createDraftTour(tx *gorm.DB, authorID uint, req dto.CreateTourRequest) (*models.Tour, error) {
 	// Validacija
 	if req.Name == "" {
 		return nil, errors.New("tour name is required")
@@ -34,45 +64,102 @@ func (s *TourService) CreateTour(authorID uint, req dto.CreateTourRequest) (*mod
 		return nil, errors.New("at least one key point is required")
 	}
 
+	if err := validatePrice(req.Price); err != nil {
+		return nil, err
+	}
+	completionMode, err := parseCompletionMode(req.CompletionMode)
+	if err != nil {
+		return nil, err
+	}
+	for _, kpReq := range req.KeyPoints {
+		if err := validateArrivalRadius(kpReq.ArrivalRadius); err != nil {
+			return nil, err
+		}
+	}
+
 	// Kreiraj turu
 	tour := &models.Tour{
-		AuthorID:    authorID,
-		Name:        req.Name,
-		Description: req.Description,
-		Difficulty:  models.TourDifficulty(req.Difficulty),
-		Tags:        req.Tags,
-		Status:      models.Draft,
-		Price:       0,
+		AuthorID:       authorID,
+		Name:           req.Name,
+		Description:    req.Description,
+		Difficulty:     models.TourDifficulty(req.Difficulty),
+		Tags:           req.Tags,
+		Status:         models.Draft,
+		Price:          req.Price,
+		CompletionMode: completionMode,
+	}
+
+	tourRepo := repository.NewTourRepository(tx)
+	if err := tourRepo.Create(tour); err != nil {
+		return nil, err
 	}
 
-	err := s.Repo.Create(tour)
+	// pocetna cena je prvi zapis u istoriji cena
+	err = tourRepo.CreatePriceHistory(&models.TourPriceHistory{
+		TourID:    tour.ID,
+		NewPrice:  tour.Price,
+		ChangedBy: authorID,
+		ChangedAt: tour.CreatedAt,
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	// Kreiraj key points
-	keyPointRepo := repository.NewKeyPointRepository(s.Repo.DB)
+	keyPointRepo := repository.NewKeyPointRepository(tx)
 	for i, kpReq := range req.KeyPoints {
 		keyPoint := &models.KeyPoint{
-			TourID:      tour.ID,
-			Name:        kpReq.Name,
-			Description: kpReq.Description,
-			Latitude:    kpReq.Latitude,
-			Longitude:   kpReq.Longitude,
-			Image:       kpReq.Image,
-			Order:       i + 1,
+			TourID:        tour.ID,
+			Name:          kpReq.Name,
+			Description:   kpReq.Description,
+			Latitude:      kpReq.Latitude,
+			Longitude:     kpReq.Longitude,
+			Image:         kpReq.Image,
+			Order:         i + 1,
+			ArrivalRadius: arrivalRadiusOrDefault(kpReq.ArrivalRadius),
+			Secret:        secretFromRequest(kpReq.Secret),
 		}
-		err = keyPointRepo.Create(keyPoint)
-		if err != nil {
+		if err := keyPointRepo.Create(keyPoint); err != nil {
 			return nil, err
 		}
 	}
 
-	// racuna distancu ako ima 2+ keypointa
-	if len(req.KeyPoints) >= 2 {
-		s.CalculateDistance(tour.ID)
+	return tour, nil
+}
+
+// parseCompletionMode proverava nacin obilaska keypointa, prazan znaci bilo kojim redosledom
+func parseCompletionMode(mode string) (models.CompletionMode, error) {
+	switch models.CompletionMode(mode) {
+	case "":
+		return models.FreeOrder, nil
+	case models.FreeOrder, models.Sequential:
+		return models.CompletionMode(mode), nil
+	}
+	return "", fmt.Errorf("invalid completion mode: %s", mode)
+}
+
+// menja nacin obilaska keypointa draft ture
+func (s *TourService) UpdateCompletionMode(tourID uint, authorID uint, req dto.UpdateCompletionModeRequest) (*models.Tour, error) {
+	mode, err := parseCompletionMode(req.CompletionMode)
+	if err != nil {
+		return nil, err
+	}
+
+	tour, err := s.Repo.FindByID(tourID)
+	if err != nil {
+		return nil, errors.New("tour not found")
+	}
+	if tour.AuthorID != authorID {
+		return nil, errors.New("unauthorized: not tour author")
+	}
+	if tour.Status != models.Draft {
+		return nil, ErrNotDraft
 	}
 
+	if err := s.Repo.UpdateFields(tourID, map[string]interface{}{"completion_mode": mode}); err != nil {
+		return nil, err
+	}
+	tour.CompletionMode = mode
 	return tour, nil
 }
 
@@ -85,46 +172,291 @@ func (s *TourService) GetAllPublishedTours() ([]models.Tour, error) {
 	return s.Repo.FindAllPublished()
 }
 
+const (
+	defaultTourPageSize = 20
+	maxTourPageSize     = 100
+)
+
+// ErrInvalidQuery oznacava neispravne parametre pretrage ili listanja, handleri ga vracaju kao 400
+var ErrInvalidQuery = errors.New("invalid query")
+
+// ErrNotTourAuthor: akciju nad turom moze da izvrsi samo njen autor
+var ErrNotTourAuthor = errors.New("unauthorized: not tour author")
+
+// ErrInvalidRequest oznacava neispravne podatke u telu zahteva, handleri ga vracaju kao 400
+var ErrInvalidRequest = errors.New("invalid request")
+
+// ErrTourNotDeleted: vracanje i trajno brisanje su moguci samo za obrisane ture
+var ErrTourNotDeleted = errors.New("tour is not deleted")
+
+// findTour ucitava turu i razlikuje nepostojecu turu od greske baze
+func findTour(find func(uint) (*models.Tour, error), tourID uint) (*models.Tour, error) {
+	tour, err := find(tourID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTourNotFound
+	}
+	return tour, err
+}
+
+// isDuplicateKey prepoznaje krsenje unique indeksa kada istovremeni zahtev vec upise isti red
+func isDuplicateKey(err error) bool {
+	return errors.Is(err, gorm.ErrDuplicatedKey) || strings.Contains(err.Error(), "duplicate key")
+}
+
+// pretrazuje publishovane ture po filterima, sortira i vraca jednu stranu rezultata
+func (s *TourService) SearchPublishedTours(query dto.TourSearchQuery) (*dto.PagedToursResponse, error) {
+	for _, d := range query.Difficulties {
+		switch models.TourDifficulty(d) {
+		case models.Easy, models.Medium, models.Hard, models.Expert:
+		default:
+			return nil, fmt.Errorf("%w: invalid difficulty: %s", ErrInvalidQuery, d)
+		}
+	}
+	if query.TransportType != "" {
+		switch models.TransportType(query.TransportType) {
+		case models.Walking, models.Bicycle, models.Car:
+		default:
+			return nil, fmt.Errorf("%w: invalid transport type: %s", ErrInvalidQuery, query.TransportType)
+		}
+	}
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		return nil, fmt.Errorf("%w: minPrice cannot be greater than maxPrice", ErrInvalidQuery)
+	}
+	if query.MinDistance != nil && query.MaxDistance != nil && *query.MinDistance > *query.MaxDistance {
+		return nil, fmt.Errorf("%w: minDistance cannot be greater than maxDistance", ErrInvalidQuery)
+	}
+	if query.MinRating != nil && (*query.MinRating < 0 || *query.MinRating > 5) {
+		return nil, fmt.Errorf("%w: minRating must be between 0 and 5", ErrInvalidQuery)
+	}
+
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 {
+		query.Limit = defaultTourPageSize
+	}
+	if query.Limit > maxTourPageSize {
+		query.Limit = maxTourPageSize
+	}
+
+	tours, total, err := s.Repo.SearchPublished(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.PagedToursResponse{
+		Results:    tours,
+		TotalCount: total,
+		Page:       query.Page,
+		Limit:      query.Limit,
+	}, nil
+}
+
+const (
+	maxNearbyRadiusKm   = 200.0
+	defaultNearbyLimit  = 20
+	maxNearbyLimit      = 100
+	earthRadiusKm       = 6371.0 // isti radijus kao u haversineDistance
+	// boundingBoxMargin siri pravougaonik za 1% da greske zaokruzivanja ne izbace ture na ivici radijusa
+	boundingBoxMargin = 0.01
+)
+
+// vraca publishovane ture cija je (pocetna ili bilo koja) kljucna tacka u radijusu od lokacije,
+// sortirane po udaljenosti najblize tacke
+func (s *TourService) FindToursNearby(lat, lng, radiusKm float64, startOnly bool, limit int) ([]dto.NearbyTourResponse, error) {
+	if lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return nil, fmt.Errorf("%w: invalid coordinates", ErrInvalidQuery)
+	}
+	if radiusKm <= 0 || radiusKm > maxNearbyRadiusKm {
+		return nil, fmt.Errorf("%w: radius must be between 0 and %.0f km", ErrInvalidQuery, maxNearbyRadiusKm)
+	}
+	if limit < 1 {
+		limit = defaultNearbyLimit
+	}
+	if limit > maxNearbyLimit {
+		limit = maxNearbyLimit
+	}
+
+	// bounding box prefilter koristi indeks nad (latitude, longitude), haversine se racuna samo za kandidate
+	minLat, maxLat, minLng, maxLng := boundingBox(lat, lng, radiusKm)
+	candidates, err := s.Repo.FindPublishedKeyPointsInBox(minLat, maxLat, minLng, maxLng, startOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	nearest := make(map[uint]dto.NearbyTourResponse)
+	for _, kp := range candidates {
+		distance := haversineDistance(lat, lng, kp.Latitude, kp.Longitude)
+		if distance > radiusKm {
+			continue
+		}
+		if current, ok := nearest[kp.TourID]; !ok || distance < current.DistanceKm {
+			nearest[kp.TourID] = dto.NearbyTourResponse{DistanceKm: distance, NearestKeyPointID: kp.ID}
+		}
+	}
+
+	tourIDs := make([]uint, 0, len(nearest))
+	for tourID := range nearest {
+		tourIDs = append(tourIDs, tourID)
+	}
+	sort.Slice(tourIDs, func(i, j int) bool {
+		di, dj := nearest[tourIDs[i]].DistanceKm, nearest[tourIDs[j]].DistanceKm
+		if di != dj {
+			return di < dj
+		}
+		return tourIDs[i] < tourIDs[j]
+	})
+	if len(tourIDs) > limit {
+		tourIDs = tourIDs[:limit]
+	}
+
+	tours, err := s.Repo.FindPublishedByIDs(tourIDs)
+	if err != nil {
+		return nil, err
+	}
+	toursByID := make(map[uint]models.Tour, len(tours))
+	for _, tour := range tours {
+		toursByID[tour.ID] = tour
+	}
+
+	results := make([]dto.NearbyTourResponse, 0, len(tourIDs))
+	for _, tourID := range tourIDs {
+		tour, ok := toursByID[tourID]
+		if !ok {
+			continue
+		}
+		result := nearest[tourID]
+		result.Tour = tour
+		results = append(results, result)
+	}
+	return results, nil
+}
+
 // vraca turu po id sa svim relacijama
 func (s *TourService) GetTourByID(tourID, userID uint, authHeader string) (*models.Tour, error) {
-	fmt.Println("------------------------------------------")
-	fmt.Printf(">>> PROVERA TURE: ID=%d, za KORISNIKA: ID=%d\n", tourID, userID)
-
 	// 1. Uvek prvo dohvati turu sa SVIM ključnim tačkama iz baze
-	tour, err := s.Repo.FindByIDWithRelations(tourID)
+	tour, err := findTour(s.Repo.FindByIDWithRelations, tourID)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(tour.KeyPoints) == 0 {
-		fmt.Println("!!! Tura nema ključne tačke, vraćam odmah.")
+	// obrisana tura je vidljiva samo turistima koji su je vec kupili
+	if tour.IsDeleted {
+		hasPurchased, err := s.PurchaseChecker.HasUserPurchasedTour(userID, tourID, authHeader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check purchase of deleted tour: %w", err)
+		}
+		if !hasPurchased {
+			return nil, ErrTourNotFound
+		}
 		return tour, nil
 	}
 
-	// 2. Proveri da li je korisnik kupio turu (ili je on autor ture)
-	isAuthor := tour.AuthorID == userID
-	fmt.Printf(">>> DA LI JE AUTOR? %t\n", isAuthor)
+	// 2. i 3. Ako korisnik NIJE autor I NIJE kupio turu, vrati samo prvu ključnu tačku kao "preview"
+	if len(tour.KeyPoints) > 0 && !s.hasFullAccess(tour, userID, authHeader) {
+		tour.KeyPoints = tour.KeyPoints[:1]
+	}
+	return tour, nil
+}
+
+// proverava da li korisnik vidi sve kljucne tacke ture (autor je ili je kupio turu)
+func (s *TourService) hasFullAccess(tour *models.Tour, userID uint, authHeader string) bool {
+	if tour.AuthorID == userID {
+		return true
+	}
 
-	fmt.Println(">>> POZIVAM SHOPPING-CART-SERVICE DA PROVERIM KUPOVINU...")
-	hasPurchased, err := s.PurchaseChecker.HasUserPurchasedTour(userID, tourID, authHeader)
+	hasPurchased, err := s.PurchaseChecker.HasUserPurchasedTour(userID, tour.ID, authHeader)
 	if err != nil {
-		fmt.Printf("!!! GREŠKA pri proveri kupovine: %v\n", err)
+		log.Printf("Failed to check purchase of tour %d for user %d: %v", tour.ID, userID, err)
 	}
-	
-	fmt.Printf(">>> REZULTAT PROVERE KUPOVINE: %t\n", hasPurchased) // <-- KLJUČNI ISPIS
+	return hasPurchased
+}
 
-	// 3. Ako korisnik NIJE autor I NIJE kupio turu, onda filtriraj
-	if !isAuthor && !hasPurchased {
-		fmt.Println(">>> USLOV ISPUNJEN: Korisnik nije autor i nije kupio turu. FILTRIRAM ključne tačke.")
-		// Vrati samo prvu ključnu tačku kao "preview"
-		tour.KeyPoints = tour.KeyPoints[:1]
-	} else {
-		fmt.Println(">>> USLOV NIJE ISPUNJEN: Vraćam sve ključne tačke.")
+// izvozi rutu ture u trazenom formatu; vazi isto pravilo vidljivosti kao GetTourByID,
+// pa tura koja nije kupljena izvozi samo preview tacku
+func (s *TourService) ExportTour(tourID, userID uint, authHeader string, format RouteFormat) ([]byte, error) {
+	tour, err := s.GetTourByID(tourID, userID, authHeader)
+	if err != nil {
+		return nil, err
 	}
-	
-	fmt.Println("------------------------------------------")
-	// 4. Vrati (potencijalno modifikovanu) turu
-	return tour, nil
+	return RenderRoute(tour, format)
+}
+
+// kreira draft turu iz GPX/GeoJSON fajla; gusti trackovi se proredjuju na MaxKeyPoints keypointa,
+// a distanca se racuna po celoj putanji
+func (s *TourService) ImportTour(authorID uint, filename string, data []byte, req dto.ImportTourRequest) (*models.Tour, error) {
+	format, err := DetectRouteFormat(filename, data)
+	if err != nil {
+		return nil, err
+	}
+	route, err := parseRoute(data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	maxKeyPoints := req.MaxKeyPoints
+	if maxKeyPoints == 0 {
+		maxKeyPoints = DefaultImportKeyPoints
+	}
+	if maxKeyPoints < 2 || maxKeyPoints > MaxImportKeyPoints {
+		return nil, fmt.Errorf("maxKeyPoints must be between 2 and %d", MaxImportKeyPoints)
+	}
+
+	path := route.Path
+	if len(path) == 0 {
+		path = route.Waypoints
+	}
+	if len(path) == 0 {
+		return nil, &ImportError{Problems: []string{"file contains no waypoints, routes or tracks"}}
+	}
+
+	// imenovane tacke autora imaju prednost, inace se keypointi biraju sa putanje
+	points := route.Waypoints
+	if len(points) < 2 || len(points) > maxKeyPoints {
+		points = downsamplePath(path, maxKeyPoints)
+	}
+
+	createReq := dto.CreateTourRequest{
+		Name:        firstNonEmpty(req.Name, route.Name, strings.TrimSuffix(filename, filepath.Ext(filename))),
+		Description: firstNonEmpty(req.Description, route.Description),
+		Difficulty:  req.Difficulty,
+		Tags:        req.Tags,
+	}
+	for i, point := range points {
+		createReq.KeyPoints = append(createReq.KeyPoints, dto.CreateKeyPointRequest{
+			Name:        firstNonEmpty(point.Name, fmt.Sprintf("Point %d", i+1)),
+			Description: point.Description,
+			Latitude:    point.Latitude,
+			Longitude:   point.Longitude,
+			Order:       i + 1,
+		})
+	}
+
+	// tura, keypointi i ruta se cuvaju u jednoj transakciji, pa neuspeo import ne ostavlja delimican draft.
+	// Uvezeni track je vec ruta ture, pa se routing servis ne poziva: distanca, deonice i predlozena
+	// trajanja se izvode iz tracka, a tura nije oznacena kao procena po vazdusnoj liniji.
+	var tour *models.Tour
+	err = s.Repo.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if tour, err = createDraftTour(tx, authorID, createReq); err != nil {
+			return err
+		}
+		return s.Routes.ApplyRoute(repository.NewTourRepository(tx), repository.NewKeyPointRepository(tx), tour.ID, trackRoute(path, points))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindByIDWithRelations(tour.ID)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
 }
 
 // dodaje trajanje u turu
@@ -137,6 +469,9 @@ func (s *TourService) AddDuration(tourID uint, authorID uint, req dto.AddDuratio
 	if tour.AuthorID != authorID {
 		return nil, errors.New("unauthorized: not tour author")
 	}
+	if tour.Status != models.Draft {
+		return nil, ErrNotDraft
+	}
 
 	// kreira trajanje
 	duration := &models.TourDuration{
@@ -150,10 +485,86 @@ func (s *TourService) AddDuration(tourID uint, authorID uint, req dto.AddDuratio
 		return nil, err
 	}
 
+	// autorovo trajanje zamenjuje predlozeno za isti tip prevoza
+	if err := s.Repo.DeleteSuggestedDuration(tourID, duration.TransportType); err != nil {
+		return nil, err
+	}
+
 	return duration, nil
 }
 
-// publishuje turu posle validacije
+// maxTourPrice je gornja granica cene ture
+const maxTourPrice = 100000.0
+
+func validatePrice(price float64) error {
+	if math.IsNaN(price) || math.IsInf(price, 0) {
+		return errors.New("invalid price")
+	}
+	if price < 0 {
+		return errors.New("price cannot be negative")
+	}
+	if price > maxTourPrice {
+		return fmt.Errorf("price cannot be greater than %.0f", maxTourPrice)
+	}
+	return nil
+}
+
+// menja cenu draft ili publishovane ture i belezi promenu u istoriji cena
+func (s *TourService) UpdateTourPrice(tourID uint, authorID uint, req dto.UpdatePriceRequest) (*models.Tour, error) {
+	if err := validatePrice(req.Price); err != nil {
+		return nil, err
+	}
+
+	tour, err := s.Repo.FindByID(tourID)
+	if err != nil {
+		return nil, errors.New("tour not found")
+	}
+	if tour.AuthorID != authorID {
+		return nil, errors.New("unauthorized: not tour author")
+	}
+	if tour.Status != models.Draft && tour.Status != models.Published {
+		return nil, errors.New("only draft and published tours can change price")
+	}
+	if tour.Price == req.Price {
+		return tour, nil
+	}
+
+	if err := s.Repo.UpdatePrice(tourID, req.Price, authorID); err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindByID(tourID)
+}
+
+// vraca istoriju cena ture (autor ili administrator), ili samo cenu vazecu u trenutku at
+func (s *TourService) GetPriceHistory(tourID uint, userID uint, isAdmin bool, at *time.Time) ([]models.TourPriceHistory, error) {
+	tour, err := s.Repo.FindByID(tourID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTourNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && tour.AuthorID != userID {
+		return nil, ErrNotTourAuthor
+	}
+
+	if at == nil {
+		return s.Repo.FindPriceHistory(tourID)
+	}
+
+	entry, err := s.Repo.FindPriceAt(tourID, *at)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return []models.TourPriceHistory{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []models.TourPriceHistory{*entry}, nil
+}
+
+// publishuje turu posle validacije i cuva je kao verziju 1;
+// draft revizija publishovane ture se umesto toga primenjuje na originalnu turu kao nova verzija
 func (s *TourService) PublishTour(tourID uint, authorID uint) (*models.Tour, error) {
 	tour, err := s.Repo.FindByIDWithRelations(tourID)
 	if err != nil {
@@ -167,20 +578,99 @@ func (s *TourService) PublishTour(tourID uint, authorID uint) (*models.Tour, err
 	}
 
 	// pravila za validaciju
+	if err := validateForPublish(tour); err != nil {
+		return nil, err
+	}
+
+	if tour.RevisionOf != nil {
+		return s.publishRevision(tour)
+	}
+
+	// publishuje turu i cuva nepromenljivu verziju u istoj transakciji
+	version := tour.Version + 1
+	err = s.Repo.DB.Transaction(func(tx *gorm.DB) error {
+		tourRepo := repository.NewTourRepository(tx)
+		if err := tourRepo.PublishTour(tourID, version); err != nil {
+			return err
+		}
+		return createVersionSnapshot(tourRepo, tourID, version)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindByIDWithRelations(tourID)
+}
+
+func validateForPublish(tour *models.Tour) error {
 	if tour.Name == "" || tour.Description == "" {
-		return nil, errors.New("tour must have name and description")
+		return errors.New("tour must have name and description")
 	}
 	if len(tour.KeyPoints) < 2 {
-		return nil, errors.New("tour must have at least 2 key points")
+		return errors.New("tour must have at least 2 key points")
+	}
+	return nil
+}
+
+// brise turu: draft se brise trajno, a publishovana ili arhivirana tura se samo oznacava kao obrisana
+// da bi kupci zadrzali pristup
+func (s *TourService) DeleteTour(tourID uint, authorID uint) error {
+	tour, err := findTour(s.Repo.FindByID, tourID)
+	if err != nil {
+		return err
+	}
+	if tour.AuthorID != authorID {
+		return ErrNotTourAuthor
+	}
+
+	if tour.Status == models.Draft {
+		return s.Repo.DB.Transaction(func(tx *gorm.DB) error {
+			return repository.NewTourRepository(tx).Delete(tourID)
+		})
 	}
 
-	// publishuje turu
-	err = s.Repo.PublishTour(tourID)
+	return s.Repo.DB.Transaction(func(tx *gorm.DB) error {
+		tourRepo := repository.NewTourRepository(tx)
+		// otvorena revizija obrisane ture vise nema smisla
+		revision, err := tourRepo.FindRevision(tourID)
+		if err != nil {
+			return err
+		}
+		if revision != nil {
+			if err := tourRepo.Delete(revision.ID); err != nil {
+				return err
+			}
+		}
+		return tourRepo.SoftDelete(tourID)
+	})
+}
+
+// vraca obrisanu turu (administrator)
+func (s *TourService) RestoreTour(tourID uint) (*models.Tour, error) {
+	tour, err := findTour(s.Repo.FindByIDIncludingDeleted, tourID)
 	if err != nil {
 		return nil, err
 	}
+	if !tour.IsDeleted {
+		return nil, ErrTourNotDeleted
+	}
 
-	return s.Repo.FindByIDWithRelations(tourID)
+	if err := s.Repo.Restore(tourID); err != nil {
+		return nil, err
+	}
+	return s.Repo.FindByID(tourID)
+}
+
+// trajno brise obrisanu turu sa svim podacima koji na nju ukazuju (administrator)
+func (s *TourService) PurgeTour(tourID uint) error {
+	tour, err := findTour(s.Repo.FindByIDIncludingDeleted, tourID)
+	if err != nil {
+		return err
+	}
+	if !tour.IsDeleted {
+		return fmt.Errorf("%w: only deleted tours can be purged", ErrTourNotDeleted)
+	}
+	return s.Repo.Purge(tourID)
 }
 
 // arhivira publishovanu turu
@@ -225,26 +715,9 @@ func (s *TourService) ActivateTour(tourID uint, authorID uint) (*models.Tour, er
 	return s.Repo.FindByID(tourID)
 }
 
-// racuna distancu po keypointima
+// racuna distancu, deonice i predlozena trajanja po keypointima
 func (s *TourService) CalculateDistance(tourID uint) error {
-	tour, err := s.Repo.FindByIDWithRelations(tourID)
-	if err != nil {
-		return err
-	}
-
-	if len(tour.KeyPoints) < 2 {
-		return nil //nema distance da se racuna
-	}
-
-	totalDistance := 0.0
-	for i := 0; i < len(tour.KeyPoints)-1; i++ {
-		kp1 := tour.KeyPoints[i]
-		kp2 := tour.KeyPoints[i+1]
-		distance := haversineDistance(kp1.Latitude, kp1.Longitude, kp2.Latitude, kp2.Longitude)
-		totalDistance += distance
-	}
-
-	return s.Repo.UpdateDistance(tourID, totalDistance)
+	return s.Routes.Recalculate(s.Repo, repository.NewKeyPointRepository(s.Repo.DB), tourID)
 }
 
 // haversineDistance calculates distance between two lat/long points in kilometers
@@ -265,3 +738,36 @@ func haversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
 
 	return earthRadius * c
 }
+
+
+// boundingBox vraca lat/lng granice pravougaonika koji sadrzi krug radijusa radiusKm oko tacke.
+// Ako pravougaonik prelazi antimeridijan, minLng je veci od maxLng.
+func boundingBox(lat, lng, radiusKm float64) (minLat, maxLat, minLng, maxLng float64) {
+	// ugaoni radijus kruga na sferi
+	angular := radiusKm / earthRadiusKm * (1 + boundingBoxMargin)
+	deltaLat := angular * 180 / math.Pi
+	minLat = math.Max(lat-deltaLat, -90)
+	maxLat = math.Min(lat+deltaLat, 90)
+
+	// blizu polova krug pokriva sve geografske duzine
+	if minLat == -90 || maxLat == 90 {
+		return minLat, maxLat, -180, 180
+	}
+
+	// najveca razlika geografskih duzina tacaka kruga je asin(sin(d/R) / cos(lat))
+	sinDeltaLng := math.Sin(angular) / math.Cos(lat*math.Pi/180)
+	if sinDeltaLng >= 1 {
+		return minLat, maxLat, -180, 180
+	}
+	deltaLng := math.Asin(sinDeltaLng) * 180 / math.Pi
+
+	minLng = lng - deltaLng
+	maxLng = lng + deltaLng
+	if minLng < -180 {
+		minLng += 360
+	}
+	if maxLng > 180 {
+		maxLng -= 360
+	}
+	return minLat, maxLat, minLng, maxLng
+}
\ No newline at end of file
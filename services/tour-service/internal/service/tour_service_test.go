@@ -0,0 +1,77 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// destination vraca tacku na udaljenosti distanceKm od (lat, lng) u pravcu bearing (stepeni)
+func destination(lat, lng, distanceKm, bearing float64) (float64, float64) {
+	toRad := math.Pi / 180
+	d := distanceKm / earthRadiusKm
+	lat1, lng1, b := lat*toRad, lng*toRad, bearing*toRad
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(b))
+	lng2 := lng1 + math.Atan2(math.Sin(b)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+	lngDeg := math.Mod(lng2/toRad+540, 360) - 180
+	return lat2 / toRad, lngDeg
+}
+
+func insideBox(lat, lng, minLat, maxLat, minLng, maxLng float64) bool {
+	if lat < minLat || lat > maxLat {
+		return false
+	}
+	if minLng <= maxLng {
+		return lng >= minLng && lng <= maxLng
+	}
+	// pravougaonik prelazi antimeridijan
+	return lng >= minLng || lng <= maxLng
+}
+
+func TestBoundingBoxContainsWholeRadius(t *testing.T) {
+	centers := []struct{ lat, lng float64 }{
+		{0, 0},
+		{45.25, 19.84},
+		{64.14, -21.94},
+		{-75, 120},
+		{52.5, 179.9},
+	}
+	for _, c := range centers {
+		for _, radius := range []float64{1, 25, maxNearbyRadiusKm} {
+			minLat, maxLat, minLng, maxLng := boundingBox(c.lat, c.lng, radius)
+			for bearing := 0.0; bearing < 360; bearing += 5 {
+				lat, lng := destination(c.lat, c.lng, radius, bearing)
+				if d := haversineDistance(c.lat, c.lng, lat, lng); math.Abs(d-radius) > 1e-6 {
+					t.Fatalf("destination is %.6f km away, expected %.6f", d, radius)
+				}
+				if !insideBox(lat, lng, minLat, maxLat, minLng, maxLng) {
+					t.Errorf("point (%.5f, %.5f) at %.0f km from (%.2f, %.2f) is outside box [%.5f..%.5f, %.5f..%.5f]",
+						lat, lng, radius, c.lat, c.lng, minLat, maxLat, minLng, maxLng)
+				}
+			}
+		}
+	}
+}
+
+func TestBoundingBoxNearPoleCoversAllLongitudes(t *testing.T) {
+	_, maxLat, minLng, maxLng := boundingBox(89.5, 10, 100)
+	if maxLat != 90 || minLng != -180 || maxLng != 180 {
+		t.Errorf("expected full longitude range near pole, got maxLat=%v lng=[%v, %v]", maxLat, minLng, maxLng)
+	}
+}
+
+func TestFindToursNearbyRejectsInvalidQuery(t *testing.T) {
+	s := &TourService{}
+	cases := map[string][3]float64{
+		"latitude out of range":  {91, 0, 10},
+		"longitude out of range": {0, 181, 10},
+		"zero radius":            {45, 19, 0},
+		"radius too large":       {45, 19, maxNearbyRadiusKm + 1},
+	}
+	for name, c := range cases {
+		if _, err := s.FindToursNearby(c[0], c[1], c[2], false, 0); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("%s: expected ErrInvalidQuery, got %v", name, err)
+		}
+	}
+}
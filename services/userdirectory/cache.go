@@ -0,0 +1,65 @@
+package userdirectory
+
+import (
+	"container/list"
+	"time"
+)
+
+// cacheEntry cuva rezultat pretrage jednog korisnika, i kada korisnik ne postoji (found=false)
+type cacheEntry struct {
+	id        uint
+	user      User
+	found     bool
+	fetchedAt time.Time
+}
+
+// lruCache je LRU kes sa TTL-om. Istekli unosi se ne brisu odmah jer sluze
+// kao rezervni odgovor kada direktorijum nije dostupan, izbacuje ih tek LRU.
+// Nije bezbedan za konkurentnu upotrebu, Client ga stiti svojim mutex-om.
+type lruCache struct {
+	ttl        time.Duration
+	maxEntries int
+	order      *list.List // najskorije korisceni na pocetku
+	items      map[uint]*list.Element
+}
+
+func newLRUCache(ttl time.Duration, maxEntries int) *lruCache {
+	return &lruCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		order:      list.New(),
+		items:      make(map[uint]*list.Element),
+	}
+}
+
+// get vraca unos i da li je jos svez
+func (c *lruCache) get(id uint, now time.Time) (*cacheEntry, bool) {
+	el, ok := c.items[id]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	entry := el.Value.(*cacheEntry)
+	return entry, now.Sub(entry.fetchedAt) < c.ttl
+}
+
+func (c *lruCache) put(entry *cacheEntry) {
+	if el, ok := c.items[entry.id]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[entry.id] = c.order.PushFront(entry)
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).id)
+	}
+}
+
+func (c *lruCache) remove(id uint) {
+	if el, ok := c.items[id]; ok {
+		c.order.Remove(el)
+		delete(c.items, id)
+	}
+}
@@ -0,0 +1,294 @@
+// Package userdirectory je zajednicki klijent za dohvatanje korisnickih imena i
+// profila iz stakeholders servisa. Pojedinacni zahtevi se spajaju u batch pozive,
+// rezultati se kesiraju (TTL + LRU), istovremeni upiti za istog korisnika dele
+// jedan poziv, a kada direktorijum nije dostupan vracaju se poslednji poznati podaci.
+package userdirectory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound    = errors.New("user not found")
+	ErrUnavailable = errors.New("user directory is unavailable")
+)
+
+const (
+	DefaultBaseURL      = "http://stakeholders-service:8080"
+	DefaultTTL          = 5 * time.Minute
+	DefaultMaxEntries   = 10000
+	DefaultBatchWindow  = 5 * time.Millisecond
+	DefaultMaxBatchSize = 100
+	DefaultRetryAfter   = 10 * time.Second
+	defaultTimeout      = 3 * time.Second
+)
+
+// User je javni profil korisnika kako ga vraca stakeholders servis
+type User struct {
+	ID           uint   `json:"id"`
+	Username     string `json:"username"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+	ProfileImage string `json:"profile_image"`
+}
+
+// Config podesava klijenta, nulte vrednosti se zamenjuju podrazumevanim
+type Config struct {
+	// BaseURL stakeholders servisa; ako je prazan koristi se USER_DIRECTORY_URL pa DefaultBaseURL
+	BaseURL string
+	// TTL posle kog se korisnik ponovo dohvata
+	TTL time.Duration
+	// MaxEntries je najveci broj korisnika u kesu
+	MaxEntries int
+	// BatchWindow je koliko se ceka da se skupe upiti u jedan poziv
+	BatchWindow time.Duration
+	// MaxBatchSize je najvise ID-eva po jednom pozivu
+	MaxBatchSize int
+	// RetryAfter je koliko se posle neuspelog poziva ne gadja direktorijum
+	RetryAfter time.Duration
+	HTTPClient *http.Client
+}
+
+// call je dohvatanje jednog korisnika u toku, svi koji ga cekaju dele rezultat
+type call struct {
+	done  chan struct{}
+	user  User
+	found bool
+	err   error
+}
+
+// Client je bezbedan za konkurentnu upotrebu i treba ga deliti u celom servisu
+type Client struct {
+	cfg   Config
+	batch string
+
+	mu               sync.Mutex
+	cache            *lruCache
+	inflight         map[uint]*call
+	queue            []uint
+	timer            *time.Timer
+	unavailableUntil time.Time
+
+	now func() time.Time // sat za TTL i RetryAfter, testovi ga zamenjuju
+}
+
+// New kreira klijenta za direktorijum korisnika
+func New(cfg Config) *Client {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = os.Getenv("USER_DIRECTORY_URL")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultTTL
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultMaxEntries
+	}
+	if cfg.BatchWindow <= 0 {
+		cfg.BatchWindow = DefaultBatchWindow
+	}
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = DefaultMaxBatchSize
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = DefaultRetryAfter
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Client{
+		cfg:      cfg,
+		batch:    strings.TrimRight(cfg.BaseURL, "/") + "/api/v1/users/batch",
+		cache:    newLRUCache(cfg.TTL, cfg.MaxEntries),
+		inflight: make(map[uint]*call),
+		now:      time.Now,
+	}
+}
+
+// Get vraca jednog korisnika. Vraca ErrNotFound ako korisnik ne postoji, odnosno
+// gresku koja obuhvata ErrUnavailable ako direktorijum nije dostupan a korisnik nije u kesu.
+func (c *Client) Get(ctx context.Context, id uint) (User, error) {
+	users, err := c.Lookup(ctx, []uint{id})
+	if user, ok := users[id]; ok {
+		return user, nil
+	}
+	if err != nil {
+		return User{}, err
+	}
+	return User{}, ErrNotFound
+}
+
+// Username vraca korisnicko ime ili fallback ako korisnik nije mogao da se dohvati
+func (c *Client) Username(ctx context.Context, id uint, fallback string) string {
+	user, err := c.Get(ctx, id)
+	if err != nil {
+		log.Printf("userdirectory: username for user %d not resolved: %v", id, err)
+		return fallback
+	}
+	return user.Username
+}
+
+// Lookup vraca poznate korisnike po ID-u; nepostojeci korisnici nisu u mapi.
+// Ako direktorijum nije dostupan vraca sve sto se moze (i zastarele unose iz kesa)
+// zajedno sa greskom koja obuhvata ErrUnavailable za ostatak.
+func (c *Client) Lookup(ctx context.Context, ids []uint) (map[uint]User, error) {
+	users := make(map[uint]User, len(ids))
+	pending := make(map[uint]*call)
+
+	c.mu.Lock()
+	now := c.now()
+	for _, id := range ids {
+		if _, seen := users[id]; seen {
+			continue
+		}
+		if _, seen := pending[id]; seen {
+			continue
+		}
+		if entry, fresh := c.cache.get(id, now); fresh {
+			if entry.found {
+				users[id] = entry.user
+			}
+			continue
+		}
+		pending[id] = c.enqueueLocked(id)
+	}
+	c.mu.Unlock()
+
+	var missing []uint
+	var lastErr error
+	for id, cl := range pending {
+		select {
+		case <-cl.done:
+		case <-ctx.Done():
+			return users, ctx.Err()
+		}
+		switch {
+		case cl.err != nil:
+			missing = append(missing, id)
+			lastErr = cl.err
+		case cl.found:
+			users[id] = cl.user
+		}
+	}
+	if len(missing) > 0 {
+		return users, fmt.Errorf("%d of %d users not resolved: %w", len(missing), len(ids), lastErr)
+	}
+	return users, nil
+}
+
+// enqueueLocked prikljucuje se dohvatanju koje je vec u toku ili dodaje ID u sledeci batch
+func (c *Client) enqueueLocked(id uint) *call {
+	if cl, ok := c.inflight[id]; ok {
+		return cl
+	}
+	cl := &call{done: make(chan struct{})}
+	c.inflight[id] = cl
+	c.queue = append(c.queue, id)
+
+	if len(c.queue) >= c.cfg.MaxBatchSize {
+		c.flushLocked()
+	} else if c.timer == nil {
+		var timer *time.Timer
+		timer = time.AfterFunc(c.cfg.BatchWindow, func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if c.timer == timer {
+				c.flushLocked()
+			}
+		})
+		c.timer = timer
+	}
+	return cl
+}
+
+func (c *Client) flushLocked() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if len(c.queue) == 0 {
+		return
+	}
+	batch := c.queue
+	c.queue = nil
+	go c.resolve(batch)
+}
+
+// resolve dohvata batch i razresava sve koji cekaju na te ID-eve
+func (c *Client) resolve(ids []uint) {
+	var fetched map[uint]User
+	var err error
+
+	c.mu.Lock()
+	backingOff := c.now().Before(c.unavailableUntil)
+	c.mu.Unlock()
+	if backingOff {
+		err = ErrUnavailable
+	} else if fetched, err = c.fetch(ids); err != nil {
+		log.Printf("userdirectory: batch lookup of %d users failed: %v", len(ids), err)
+		err = fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if err != nil && !backingOff {
+		c.unavailableUntil = now.Add(c.cfg.RetryAfter)
+	}
+	for _, id := range ids {
+		cl := c.inflight[id]
+		delete(c.inflight, id)
+
+		if err == nil {
+			user, found := fetched[id]
+			c.cache.put(&cacheEntry{id: id, user: user, found: found, fetchedAt: now})
+			cl.user, cl.found = user, found
+		} else if stale, _ := c.cache.get(id, now); stale != nil {
+			// poslednji poznati podaci su bolji od greske dok direktorijum ne proradi
+			cl.user, cl.found = stale.user, stale.found
+		} else {
+			cl.err = err
+		}
+		close(cl.done)
+	}
+}
+
+func (c *Client) fetch(ids []uint) (map[uint]User, error) {
+	params := make([]string, len(ids))
+	for i, id := range ids {
+		params[i] = strconv.FormatUint(uint64(id), 10)
+	}
+
+	resp, err := c.cfg.HTTPClient.Get(c.batch + "?ids=" + strings.Join(params, ","))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var users []User
+	if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
+		return nil, fmt.Errorf("failed to parse users: %w", err)
+	}
+
+	result := make(map[uint]User, len(users))
+	for _, user := range users {
+		result[user.ID] = user
+	}
+	return result, nil
+}
@@ -0,0 +1,276 @@
+package userdirectory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// directoryServer je lazni stakeholders servis koji belezi svaki batch poziv
+type directoryServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	batches [][]uint
+	failing bool
+	release chan struct{} // ako nije nil, odgovor ceka dok se ne zatvori
+}
+
+func newDirectoryServer(t *testing.T) *directoryServer {
+	t.Helper()
+	s := &directoryServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveBatch))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *directoryServer) serveBatch(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/v1/users/batch" {
+		http.NotFound(w, r)
+		return
+	}
+	var ids []uint
+	for _, raw := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		id, _ := strconv.ParseUint(raw, 10, 32)
+		ids = append(ids, uint(id))
+	}
+
+	s.mu.Lock()
+	s.batches = append(s.batches, ids)
+	failing, release := s.failing, s.release
+	s.mu.Unlock()
+
+	if release != nil {
+		<-release
+	}
+	if failing {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	// korisnici sa ID-em vecim od 1000 ne postoje
+	users := []User{}
+	for _, id := range ids {
+		if id <= 1000 {
+			users = append(users, User{ID: id, Username: "user" + strconv.Itoa(int(id))})
+		}
+	}
+	json.NewEncoder(w).Encode(users)
+}
+
+func (s *directoryServer) setFailing(failing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = failing
+}
+
+func (s *directoryServer) requests() [][]uint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]uint(nil), s.batches...)
+}
+
+// fakeClock je sat koji se pomera samo rucno
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestClient(server *directoryServer, cfg Config) (*Client, *fakeClock) {
+	cfg.BaseURL = server.URL
+	if cfg.BatchWindow == 0 {
+		cfg.BatchWindow = time.Millisecond
+	}
+	client := New(cfg)
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	client.now = clock.Now
+	return client, clock
+}
+
+func mustGet(t *testing.T, client *Client, id uint) User {
+	t.Helper()
+	user, err := client.Get(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Get(%d): %v", id, err)
+	}
+	return user
+}
+
+func TestConcurrentGetsShareOneRequest(t *testing.T) {
+	server := newDirectoryServer(t)
+	server.release = make(chan struct{})
+	client, _ := newTestClient(server, Config{BatchWindow: 50 * time.Millisecond})
+
+	const callers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := client.Get(context.Background(), 7)
+			if err == nil && user.Username != "user7" {
+				err = errors.New("unexpected username " + user.Username)
+			}
+			errs <- err
+		}()
+	}
+	// i oni koji stignu dok je poziv u toku treba da ga dele
+	time.Sleep(100 * time.Millisecond)
+	close(server.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := server.requests(); len(got) != 1 || len(got[0]) != 1 || got[0][0] != 7 {
+		t.Errorf("expected a single request for user 7, got %v", got)
+	}
+}
+
+func TestLookupSplitsBatchesAtMaxBatchSize(t *testing.T) {
+	server := newDirectoryServer(t)
+	client, _ := newTestClient(server, Config{MaxBatchSize: 3})
+
+	ids := []uint{1, 2, 3, 4, 5, 6, 7}
+	users, err := client.Lookup(context.Background(), ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != len(ids) {
+		t.Errorf("expected %d users, got %d", len(ids), len(users))
+	}
+
+	var sizes []int
+	requested := map[uint]int{}
+	for _, batch := range server.requests() {
+		sizes = append(sizes, len(batch))
+		for _, id := range batch {
+			requested[id]++
+		}
+	}
+	sort.Ints(sizes)
+	if len(sizes) != 3 || sizes[0] != 1 || sizes[1] != 3 || sizes[2] != 3 {
+		t.Errorf("expected batches of 3, 3 and 1 ids, got sizes %v", sizes)
+	}
+	for _, id := range ids {
+		if requested[id] != 1 {
+			t.Errorf("user %d requested %d times", id, requested[id])
+		}
+	}
+}
+
+func TestGetRefetchesAfterTTL(t *testing.T) {
+	server := newDirectoryServer(t)
+	client, clock := newTestClient(server, Config{TTL: time.Minute})
+
+	mustGet(t, client, 1)
+	clock.Advance(59 * time.Second)
+	mustGet(t, client, 1)
+	if n := len(server.requests()); n != 1 {
+		t.Fatalf("expected a fresh entry to be served from cache, got %d requests", n)
+	}
+
+	clock.Advance(2 * time.Second)
+	mustGet(t, client, 1)
+	if n := len(server.requests()); n != 2 {
+		t.Errorf("expected an expired entry to be fetched again, got %d requests", n)
+	}
+}
+
+func TestGetCachesMissingUsers(t *testing.T) {
+	server := newDirectoryServer(t)
+	client, _ := newTestClient(server, Config{})
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(context.Background(), 2000); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+	}
+	if n := len(server.requests()); n != 1 {
+		t.Errorf("expected the missing user to be cached, got %d requests", n)
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	server := newDirectoryServer(t)
+	client, _ := newTestClient(server, Config{MaxEntries: 2})
+
+	mustGet(t, client, 1)
+	mustGet(t, client, 2)
+	mustGet(t, client, 1) // 2 postaje najdavnije koriscen
+	mustGet(t, client, 3)
+
+	before := len(server.requests())
+	mustGet(t, client, 1)
+	mustGet(t, client, 3)
+	if n := len(server.requests()); n != before {
+		t.Fatalf("expected users 1 and 3 to stay cached, got %d new requests", n-before)
+	}
+
+	mustGet(t, client, 2)
+	if n := len(server.requests()); n != before+1 {
+		t.Errorf("expected evicted user 2 to be fetched again, got %d new requests", n-before)
+	}
+}
+
+func TestStaleEntriesServedWhileDirectoryUnavailable(t *testing.T) {
+	server := newDirectoryServer(t)
+	client, clock := newTestClient(server, Config{TTL: time.Minute, RetryAfter: 10 * time.Second})
+
+	mustGet(t, client, 1)
+	clock.Advance(2 * time.Minute)
+	server.setFailing(true)
+
+	if user := mustGet(t, client, 1); user.Username != "user1" {
+		t.Fatalf("expected stale user1, got %q", user.Username)
+	}
+	if _, err := client.Get(context.Background(), 2); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable for an uncached user, got %v", err)
+	}
+
+	// tokom RetryAfter direktorijum se ne poziva, a zastareli podaci se i dalje vracaju
+	failed := len(server.requests())
+	clock.Advance(5 * time.Second)
+	if user := mustGet(t, client, 1); user.Username != "user1" {
+		t.Fatalf("expected stale user1 during backoff, got %q", user.Username)
+	}
+	if _, err := client.Get(context.Background(), 2); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable during backoff, got %v", err)
+	}
+	if n := len(server.requests()); n != failed {
+		t.Fatalf("expected no requests during backoff, got %d", n-failed)
+	}
+
+	server.setFailing(false)
+	clock.Advance(6 * time.Second)
+	if user := mustGet(t, client, 2); user.Username != "user2" {
+		t.Errorf("expected user2 after the directory recovered, got %q", user.Username)
+	}
+	if n := len(server.requests()); n != failed+1 {
+		t.Errorf("expected one request after backoff, got %d", n-failed)
+	}
+}
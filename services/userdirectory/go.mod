@@ -0,0 +1,3 @@
+module userdirectory
+
+go 1.21
@@ -10,6 +10,9 @@ import { Tour } from '../../tour/model/tour.model';
 export class HomeComponent implements OnInit {
   publishedTours: Tour[] = [];
   isLoading = true;
+  page = 1;
+  readonly pageSize = 12;
+  totalCount = 0;
 
   constructor(private tourService: TourService) {}
 
@@ -17,11 +20,17 @@ export class HomeComponent implements OnInit {
     this.loadPublishedTours();
   }
 
-  loadPublishedTours(): void {
+  get totalPages(): number {
+    return Math.max(1, Math.ceil(this.totalCount / this.pageSize));
+  }
+
+  loadPublishedTours(page: number = this.page): void {
     this.isLoading = true;
-    this.tourService.getAllPublishedTours().subscribe({
-      next: (tours) => {
-        this.publishedTours = tours;
+    this.tourService.getPublishedTours(page, this.pageSize).subscribe({
+      next: (result) => {
+        this.publishedTours = result.results || [];
+        this.totalCount = result.totalCount;
+        this.page = result.page;
         this.isLoading = false;
       },
       error: (err) => {
@@ -30,4 +39,12 @@ export class HomeComponent implements OnInit {
       }
     });
   }
+
+  goToPage(page: number): void {
+    if (page < 1 || page > this.totalPages || page === this.page) {
+      return;
+    }
+    this.loadPublishedTours(page);
+    window.scrollTo({ top: 0, behavior: 'smooth' });
+  }
 }
@@ -65,8 +65,14 @@ export class CartComponent implements OnInit {
       },
       error: (err) => {
         console.error('Checkout failed:', err);
-        this.snackBar.open('Checkout failed. Please try again.', 'Dismiss', { duration: 5000 });
         this.checkoutInProgress = false;
+        if (err.status === 409) {
+          // ture u korpi su se promenile (cena ili dostupnost), backend je vec azurirao korpu
+          this.snackBar.open(`${err.error} Please review your cart.`, 'Dismiss', { duration: 8000 });
+          this.loadCart();
+          return;
+        }
+        this.snackBar.open(typeof err.error === 'string' ? err.error : 'Checkout failed. Please try again.', 'Dismiss', { duration: 5000 });
       }
     });
   }
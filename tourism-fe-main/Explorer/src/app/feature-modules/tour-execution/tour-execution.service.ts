@@ -2,6 +2,7 @@ import { Injectable } from '@angular/core';
 import { HttpClient } from '@angular/common/http';
 import { Observable } from 'rxjs';
 import { environment } from 'src/env/environment';
+import { ACCESS_TOKEN } from 'src/app/shared/constants';
 
 @Injectable({
   providedIn: 'root'
@@ -26,6 +27,25 @@ export class TourExecutionService {
   return this.http.post(`${this.apiUrl}/executions/${executionId}/check-position`, payload);
 }
 
+  // Live kanal izvrsavanja (SSE). EventSource sam ponovo uspostavlja vezu i salje Last-Event-ID.
+  streamExecutionEvents(executionId: number): Observable<{ type: string, data: any }> {
+    return new Observable(observer => {
+      const token = localStorage.getItem(ACCESS_TOKEN) || '';
+      const source = new EventSource(`${this.apiUrl}/executions/${executionId}/events?access_token=${encodeURIComponent(token)}`);
+      const types = ['state', 'keypoint_reached', 'next_keypoint', 'complete_eligible', 'badge_earned', 'completed', 'abandoned'];
+
+      types.forEach(type => source.addEventListener(type, (event: MessageEvent) => {
+        observer.next({ type, data: JSON.parse(event.data) });
+        if (type === 'completed' || type === 'abandoned') {
+          source.close();
+          observer.complete();
+        }
+      }));
+
+      return () => source.close();
+    });
+  }
+
   completeTour(executionId: number): Observable<any> {
     return this.http.put(`${this.apiUrl}/executions/${executionId}/complete`, {});
   }
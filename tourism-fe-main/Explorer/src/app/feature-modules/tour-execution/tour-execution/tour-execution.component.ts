@@ -3,6 +3,7 @@ import { ActivatedRoute, Router } from '@angular/router';
 import { TourExecutionService } from '../tour-execution.service';
 import { TourService } from '../../tour/tour.service';
 import { PositionSimulatorService } from '../../position-simulator/position-simulator.service';
+import { Subscription } from 'rxjs';
 
 @Component({
   selector: 'xp-tour-execution',
@@ -16,6 +17,10 @@ export class TourExecutionComponent implements OnInit, OnDestroy {
   positionInterval: any;
   currentPosition: any;
   isLoading: boolean = true;
+  earnedBadges: string[] = [];
+  nextKeyPoint: any = null;
+  distanceToNextKm: number | null = null;
+  private eventsSubscription?: Subscription;
   
   constructor(
     private route: ActivatedRoute,
@@ -31,12 +36,29 @@ export class TourExecutionComponent implements OnInit, OnDestroy {
     this.loadExecution();
     this.startPositionTracking();
     this.loadCurrentPosition();
+    this.listenForEvents();
   }
 
   ngOnDestroy(): void {
     if (this.positionInterval) {
       clearInterval(this.positionInterval);
     }
+    this.eventsSubscription?.unsubscribe();
+  }
+
+  // Bedzevi osvojeni zavrsavanjem ture stizu preko live kanala, pre dogadjaja "completed"
+  listenForEvents(): void {
+    this.eventsSubscription = this.tourExecutionService.streamExecutionEvents(this.executionId).subscribe({
+      next: (event) => {
+        if (event.type === 'badge_earned') {
+          const code = event.data?.data?.code;
+          if (code && !this.earnedBadges.includes(code)) {
+            this.earnedBadges = [...this.earnedBadges, code];
+          }
+        }
+      },
+      error: (err) => console.error('Error on execution events:', err)
+    });
   }
 
   loadExecution(): void {
@@ -99,8 +121,11 @@ checkPosition(): void {
     this.currentPosition.lat, 
     this.currentPosition.lng
   ).subscribe({
-    next: (completedKeyPoints: number[]) => { // DIREKTNO ARRAY, NE OBJEKAT
+    next: (result: any) => {
+      const completedKeyPoints: number[] = result?.newlyCompleted || [];
       console.log('✅ Position check successful. Completed key points:', completedKeyPoints);
+      this.nextKeyPoint = result?.nextKeyPoint || null;
+      this.distanceToNextKm = result?.distanceToNextKm ?? null;
       
       // Ažuriraj execution sa novim completed key points
       if (completedKeyPoints && completedKeyPoints.length > 0) {
@@ -155,7 +180,8 @@ canCompleteTour(): boolean {
       this.tourExecutionService.completeTour(this.executionId).subscribe({
         next: (response) => {
           console.log('✅ Tour completed:', response);
-          alert('Tour completed successfully! 🎉');
+          const badges = this.earnedBadges.length > 0 ? `\nNew badges: ${this.earnedBadges.join(', ')} 🏅` : '';
+          alert(`Tour completed successfully! 🎉${badges}`);
           this.execution.status = 'COMPLETED';
           this.router.navigate(['/tours', this.tour.id]);
         },
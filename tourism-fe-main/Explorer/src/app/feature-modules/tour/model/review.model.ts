@@ -8,10 +8,36 @@ export interface Review {
   comment: string;
   visitDate: string;
   images: string[];
+  helpfulCount: number;
+  notHelpfulCount: number;
+  moderationStatus: 'Visible' | 'Hidden' | 'Approved' | 'Removed';
+  authorReply?: string;
+  authorReplyAt?: string;
+  authorReplyUpdatedAt?: string;
+  replyUnread: boolean; // turista jos nije video odgovor autora
   createdAt: string;
   updatedAt: string;
 }
 
+export type ReportReason = 'spam' | 'offensive' | 'fake' | 'off_topic' | 'other';
+
+export type ReviewSort = 'newest' | 'highest' | 'lowest' | 'most_helpful';
+
+export interface ReviewListParams {
+  page?: number;
+  limit?: number;
+  sort?: ReviewSort;
+  ratings?: number[];
+  withPhotos?: boolean;
+}
+
+export interface PagedReviews {
+  results: Review[];
+  totalCount: number;
+  page: number;
+  limit: number;
+}
+
 export interface CreateReviewRequest {
   tourId: number;
   rating: number;
@@ -30,4 +56,13 @@ export interface UpdateReviewRequest {
 export interface ReviewStats {
   averageRating: number;
   reviewCount: number;
+  histogram: { [rating: number]: number };
+  trend: RatingTrend[];
+}
+
+export interface RatingTrend {
+  days: number;
+  reviewCount: number;
+  averageRating: number;
+  change?: number; // prosek u periodu minus prosek pre njega
 }
@@ -8,6 +8,9 @@ export interface Tour {
   status: 'Draft' | 'Published' | 'Archived';
   price: number;
   distance?: number;
+  routeEstimated?: boolean;
+  averageRating?: number;
+  reviewCount?: number;
   publishedAt?: string;
   archivedAt?: string;
   createdAt: string;
@@ -16,6 +19,13 @@ export interface Tour {
   keyPoints?: KeyPoint[];
 }
 
+export interface PagedTours {
+  results: Tour[];
+  totalCount: number;
+  page: number;
+  limit: number;
+}
+
 export interface KeyPoint {
   id: number;
   tourId: number;
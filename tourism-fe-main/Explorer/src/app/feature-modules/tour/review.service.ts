@@ -1,8 +1,8 @@
 import { Injectable } from '@angular/core';
-import { HttpClient, HttpHeaders } from '@angular/common/http';
+import { HttpClient, HttpHeaders, HttpParams } from '@angular/common/http';
 import { Observable } from 'rxjs';
 import { map } from 'rxjs/operators';
-import { CreateReviewRequest, Review, ReviewStats, UpdateReviewRequest } from './model/review.model';
+import { CreateReviewRequest, PagedReviews, ReportReason, Review, ReviewListParams, ReviewStats, UpdateReviewRequest } from './model/review.model';
 import { environment } from 'src/env/environment';
 
 @Injectable({
@@ -32,10 +32,20 @@ export class ReviewService {
     );
   }
 
-  // Get all reviews for a tour
-  getReviewsByTour(tourId: number): Observable<Review[]> {
-    return this.http.get<any[]>(`${this.baseUrl}/${tourId}/reviews`).pipe(
-      map(reviews => reviews.map(review => this.parseReview(review)))
+  // Get one page of reviews for a tour
+  getReviewsByTour(tourId: number, options: ReviewListParams = {}): Observable<PagedReviews> {
+    let params = new HttpParams();
+    if (options.page) params = params.set('page', options.page);
+    if (options.limit) params = params.set('limit', options.limit);
+    if (options.sort) params = params.set('sort', options.sort);
+    if (options.ratings && options.ratings.length > 0) params = params.set('rating', options.ratings.join(','));
+    if (options.withPhotos) params = params.set('withPhotos', 'true');
+
+    return this.http.get<any>(`${this.baseUrl}/${tourId}/reviews`, { params }).pipe(
+      map(page => ({
+        ...page,
+        results: (page.results || []).map((review: any) => this.parseReview(review))
+      }))
     );
   }
 
@@ -92,6 +102,74 @@ export class ReviewService {
     );
   }
 
+  // Create or edit the tour author's reply to a review
+  replyToReview(reviewId: number, text: string): Observable<Review> {
+    return this.http.put<any>(
+      `${this.baseUrl}/reviews/${reviewId}/reply`,
+      { text },
+      { headers: this.getAuthHeaders() }
+    ).pipe(
+      map(review => this.parseReview(review))
+    );
+  }
+
+  // Remove the tour author's reply from a review
+  deleteReply(reviewId: number): Observable<void> {
+    return this.http.delete<void>(
+      `${this.baseUrl}/reviews/${reviewId}/reply`,
+      { headers: this.getAuthHeaders() }
+    );
+  }
+
+  // Mark the author's reply to the user's own review as seen
+  markReplyRead(reviewId: number): Observable<void> {
+    return this.http.put<void>(
+      `${this.baseUrl}/reviews/${reviewId}/reply/read`,
+      {},
+      { headers: this.getAuthHeaders() }
+    );
+  }
+
+  // Number of the user's reviews with an author reply they have not seen yet
+  getUnreadReplyCount(): Observable<number> {
+    return this.http.get<{ unreadReplies: number }>(
+      `${this.baseUrl}/my-reviews/unread-replies`,
+      { headers: this.getAuthHeaders() }
+    ).pipe(
+      map(response => response.unreadReplies)
+    );
+  }
+
+  // Vote whether a review is helpful; a new vote replaces the previous one
+  voteReview(reviewId: number, helpful: boolean): Observable<Review> {
+    return this.http.put<any>(
+      `${this.baseUrl}/reviews/${reviewId}/vote`,
+      { helpful },
+      { headers: this.getAuthHeaders() }
+    ).pipe(
+      map(review => this.parseReview(review))
+    );
+  }
+
+  // Withdraw the user's vote on a review
+  removeVote(reviewId: number): Observable<Review> {
+    return this.http.delete<any>(
+      `${this.baseUrl}/reviews/${reviewId}/vote`,
+      { headers: this.getAuthHeaders() }
+    ).pipe(
+      map(review => this.parseReview(review))
+    );
+  }
+
+  // Report an abusive review
+  reportReview(reviewId: number, reason: ReportReason, details: string = ''): Observable<void> {
+    return this.http.post<void>(
+      `${this.baseUrl}/reviews/${reviewId}/report`,
+      { reason, details },
+      { headers: this.getAuthHeaders() }
+    );
+  }
+
   // Get all reviews by the authenticated user
   getMyReviews(): Observable<Review[]> {
     return this.http.get<any[]>(
@@ -4,7 +4,7 @@ import { MatDialog } from '@angular/material/dialog';
 import { TourService } from '../tour.service';
 import { ReviewService } from '../review.service';
 import { Tour } from '../model/tour.model';
-import { Review, ReviewStats } from '../model/review.model';
+import { ReportReason, Review, ReviewSort, ReviewStats } from '../model/review.model';
 import { MapService } from '../services/map-service.service';
 import { ReviewDialogComponent } from '../review-dialog/review-dialog.component';
 import { CartService } from '../../shopping-cart/services/cart.service';
@@ -24,6 +24,26 @@ export class TourDetailsComponent implements OnInit {
   reviews: Review[] = [];
   reviewStats: ReviewStats | null = null;
   loadingReviews = false;
+  reviewPage = 1;
+  reviewTotalCount = 0;
+  reviewSort: ReviewSort = 'newest';
+  reviewRatingFilter: number | null = null;
+  reviewWithPhotos = false;
+  readonly reviewPageSize = 10;
+  replyingToReviewId: number | null = null;
+  replyText = '';
+  savingReply = false;
+  reportingReviewId: number | null = null;
+  reportReason: ReportReason = 'spam';
+  reportDetails = '';
+  reportedReviewIds = new Set<number>();
+  readonly reportReasons: { value: ReportReason, label: string }[] = [
+    { value: 'spam', label: 'Spam' },
+    { value: 'offensive', label: 'Offensive' },
+    { value: 'fake', label: 'Fake review' },
+    { value: 'off_topic', label: 'Off topic' },
+    { value: 'other', label: 'Other' }
+  ];
 
   isAddingToCart: boolean = false; 
 
@@ -106,12 +126,22 @@ export class TourDetailsComponent implements OnInit {
   });
 }
 
-  loadReviews(tourId: number): void {
+  // ucitava prvu stranu recenzija, ili sledecu kada je append true
+  loadReviews(tourId: number, append: boolean = false): void {
     this.loadingReviews = true;
-    this.reviewService.getReviewsByTour(tourId).subscribe({
-      next: (reviews) => {
-        this.reviews = reviews;
+    this.reviewPage = append ? this.reviewPage + 1 : 1;
+    this.reviewService.getReviewsByTour(tourId, {
+      page: this.reviewPage,
+      limit: this.reviewPageSize,
+      sort: this.reviewSort,
+      ratings: this.reviewRatingFilter ? [this.reviewRatingFilter] : [],
+      withPhotos: this.reviewWithPhotos
+    }).subscribe({
+      next: (page) => {
+        this.reviews = append ? this.reviews.concat(page.results) : page.results;
+        this.reviewTotalCount = page.totalCount;
         this.loadingReviews = false;
+        this.markOwnRepliesRead(page.results);
       },
       error: (err) => {
         console.error('Error loading reviews:', err);
@@ -120,6 +150,124 @@ export class TourDetailsComponent implements OnInit {
     });
   }
 
+  // turista je video odgovor autora na svoju recenziju; oznaka "New" ostaje do sledeceg ucitavanja
+  private markOwnRepliesRead(reviews: Review[]): void {
+    const currentUser = this.authService.user$.getValue();
+    reviews
+      .filter(review => review.replyUnread && review.touristId === currentUser.id)
+      .forEach(review => this.reviewService.markReplyRead(review.id).subscribe({
+        error: (err) => console.error('Error marking reply as read:', err)
+      }));
+  }
+
+  isOwnReview(review: Review): boolean {
+    return review.touristId === this.authService.user$.getValue().id;
+  }
+
+  startReply(review: Review): void {
+    this.replyingToReviewId = review.id;
+    this.replyText = review.authorReply || '';
+  }
+
+  cancelReply(): void {
+    this.replyingToReviewId = null;
+    this.replyText = '';
+  }
+
+  saveReply(review: Review): void {
+    if (!this.replyText.trim()) {
+      return;
+    }
+    this.savingReply = true;
+    this.reviewService.replyToReview(review.id, this.replyText).subscribe({
+      next: (updated) => {
+        Object.assign(review, updated);
+        this.savingReply = false;
+        this.cancelReply();
+      },
+      error: (err) => {
+        console.error('Error saving reply:', err);
+        this.savingReply = false;
+        this.snackBar.open(typeof err.error === 'string' && err.error ? err.error : 'Failed to save reply.', 'Close', { duration: 3000 });
+      }
+    });
+  }
+
+  deleteReply(review: Review): void {
+    this.reviewService.deleteReply(review.id).subscribe({
+      next: () => {
+        review.authorReply = undefined;
+        review.authorReplyAt = undefined;
+        review.authorReplyUpdatedAt = undefined;
+      },
+      error: (err) => console.error('Error deleting reply:', err)
+    });
+  }
+
+  voteReview(review: Review, helpful: boolean): void {
+    this.reviewService.voteReview(review.id, helpful).subscribe({
+      next: (updated) => {
+        review.helpfulCount = updated.helpfulCount;
+        review.notHelpfulCount = updated.notHelpfulCount;
+      },
+      error: (err) => {
+        console.error('Error voting on review:', err);
+        this.snackBar.open(typeof err.error === 'string' && err.error ? err.error : 'Failed to vote.', 'Close', { duration: 3000 });
+      }
+    });
+  }
+
+  startReport(review: Review): void {
+    this.reportingReviewId = review.id;
+    this.reportReason = 'spam';
+    this.reportDetails = '';
+  }
+
+  cancelReport(): void {
+    this.reportingReviewId = null;
+  }
+
+  submitReport(review: Review): void {
+    this.reviewService.reportReview(review.id, this.reportReason, this.reportDetails).subscribe({
+      next: () => {
+        this.reportedReviewIds.add(review.id);
+        this.reportingReviewId = null;
+        this.snackBar.open('Thank you, the review was reported.', 'Close', { duration: 3000 });
+      },
+      error: (err) => {
+        console.error('Error reporting review:', err);
+        this.snackBar.open(typeof err.error === 'string' && err.error ? err.error : 'Failed to report review.', 'Close', { duration: 3000 });
+      }
+    });
+  }
+
+  get hasMoreReviews(): boolean {
+    return this.reviews.length < this.reviewTotalCount;
+  }
+
+  onReviewSortChange(sort: string): void {
+    this.reviewSort = sort as ReviewSort;
+    this.loadReviews(this.tour!.id);
+  }
+
+  onReviewRatingFilterChange(rating: string): void {
+    this.reviewRatingFilter = rating ? Number(rating) : null;
+    this.loadReviews(this.tour!.id);
+  }
+
+  onReviewWithPhotosChange(withPhotos: boolean): void {
+    this.reviewWithPhotos = withPhotos;
+    this.loadReviews(this.tour!.id);
+  }
+
+  // udeo recenzija sa datom ocenom, za sirinu trake u histogramu
+  getRatingShare(rating: number): number {
+    if (!this.reviewStats || this.reviewStats.reviewCount === 0) {
+      return 0;
+    }
+    return (this.reviewStats.histogram[rating] || 0) / this.reviewStats.reviewCount * 100;
+  }
+
   loadReviewStats(tourId: number): void {
     this.reviewService.getTourRatingStats(tourId).subscribe({
       next: (stats) => {
@@ -197,7 +345,7 @@ export class TourDetailsComponent implements OnInit {
           },
           error: (err) => {
             console.error('Error creating review:', err);
-            alert('Failed to create review. Please try again.');
+            alert(typeof err.error === 'string' && err.error ? err.error : 'Failed to create review. Please try again.');
           }
         });
       }
@@ -1,7 +1,7 @@
-import { HttpClient } from '@angular/common/http';
+import { HttpClient, HttpParams } from '@angular/common/http';
 import { Injectable } from '@angular/core';
 import { environment } from 'src/env/environment';
-import { Tour } from './model/tour.model';
+import { PagedTours, Tour } from './model/tour.model';
 import { Observable } from 'rxjs';
 import { CreateTourPayload } from './dto/tour-creation.dto';
 
@@ -22,8 +22,9 @@ export class TourService {
     return this.http.get<Tour[]>(this.apiUrl);
   }
 
-  getAllPublishedTours(): Observable<Tour[]> {
-    return this.http.get<Tour[]>(`${this.apiUrl}/published`);
+  getPublishedTours(page = 1, limit = 20): Observable<PagedTours> {
+    const params = new HttpParams().set('page', page).set('limit', limit);
+    return this.http.get<PagedTours>(`${this.apiUrl}/published`, { params });
   }
 
   getTourById(tourId: number): Observable<Tour> {